@@ -11,9 +11,11 @@ import (
 	"github.com/openshift-online/rh-trex/cmd/trex/servecmd"
 
 	// Import plugins to trigger their init() functions
+	_ "github.com/openshift-online/rh-trex/plugins/auditlogs"
 	_ "github.com/openshift-online/rh-trex/plugins/dinosaurs"
 	_ "github.com/openshift-online/rh-trex/plugins/events"
 	_ "github.com/openshift-online/rh-trex/plugins/generic"
+	_ "github.com/openshift-online/rh-trex/plugins/savedsearches"
 )
 
 // nolint
@@ -34,6 +34,9 @@ func runMigrate(_ *cobra.Command, _ []string) {
 		glog.Fatal(err)
 	}
 
+	// the statement and lock timeouts bound the API sessions, the migrations run without them
+	dbConfig.StatementTimeout = 0
+	dbConfig.LockTimeout = 0
 	connection := db_session.NewProdFactory(dbConfig)
 	if err := db.Migrate(connection.New(context.Background())); err != nil {
 		glog.Fatal(err)
@@ -50,5 +50,10 @@ func runServe(cmd *cobra.Command, args []string) {
 		controllersServer.Start()
 	}()
 
+	go func() {
+		purgeJob := server.NewPurgeJob()
+		purgeJob.Start()
+	}()
+
 	select {}
 }
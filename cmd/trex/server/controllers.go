@@ -30,8 +30,9 @@ func NewControllersServer() *ControllersServer {
 
 	s := &ControllersServer{
 		KindControllerManager: controllers.NewKindControllerManager(
-			db.NewAdvisoryLockFactory(env().Database.SessionFactory),
+			db.NewAdvisoryLockFactory(env().Database.SessionFactory, env().Config.Database),
 			events.Service(&env().Services),
+			env().Database.SessionFactory,
 		),
 	}
 
@@ -9,18 +9,29 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/db"
 	"github.com/openshift-online/rh-trex/pkg/handlers"
 	"github.com/openshift-online/rh-trex/pkg/logger"
+	"github.com/openshift-online/rh-trex/pkg/services"
 )
 
 func NewMetricsServer() Server {
 	mainRouter := mux.NewRouter()
 	mainRouter.NotFoundHandler = http.HandlerFunc(api.SendNotFound)
 
+	// database connection pool metrics
+	check(db.RegisterPoolMetrics(env().Database.SessionFactory), "Unable to register database pool metrics")
+	// lists rejected by the query limits
+	check(services.RegisterQueryGuardMetrics(), "Unable to register query guard metrics")
+
 	// metrics endpoint
 	prometheusMetricsHandler := handlers.NewPrometheusMetricsHandler()
 	mainRouter.Handle("/metrics", prometheusMetricsHandler.Handler())
 
+	// advisory locks held by this process
+	advisoryLocksHandler := handlers.NewAdvisoryLocksHandler()
+	mainRouter.HandleFunc("/advisory_locks", advisoryLocksHandler.List).Methods(http.MethodGet)
+
 	var mainHandler http.Handler = mainRouter
 
 	s := &metricsServer{}
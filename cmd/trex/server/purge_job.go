@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift-online/rh-trex/cmd/trex/environments"
+	"github.com/openshift-online/rh-trex/pkg/config"
+	"github.com/openshift-online/rh-trex/pkg/db"
+	"github.com/openshift-online/rh-trex/pkg/logger"
+)
+
+// PurgeFunc permanently deletes the records of a kind that are past their retention period
+// and returns how many were deleted.
+type PurgeFunc func(ctx context.Context, retention *config.RetentionConfig) (int64, error)
+
+type PurgeRegistrationFunc func(services *environments.Services) PurgeFunc
+
+var purgeRegistry = make(map[string]PurgeRegistrationFunc)
+
+// RegisterPurge registers the purge of a kind with the purge job
+func RegisterPurge(name string, registrationFunc PurgeRegistrationFunc) {
+	purgeRegistry[name] = registrationFunc
+}
+
+func NewPurgeJob() *PurgeJob {
+	j := &PurgeJob{
+		lockFactory: db.NewAdvisoryLockFactory(env().Database.SessionFactory, env().Config.Database),
+		retention:   env().Config.Retention,
+		purges:      map[string]PurgeFunc{},
+	}
+	for name, registrationFunc := range purgeRegistry {
+		j.purges[name] = registrationFunc(&env().Services)
+	}
+	return j
+}
+
+// PurgeJob periodically runs the registered purges.
+// Only one replica runs them at a time, the others skip the run.
+type PurgeJob struct {
+	lockFactory db.LockFactory
+	retention   *config.RetentionConfig
+	purges      map[string]PurgeFunc
+}
+
+// Start is a blocking call that runs the purges every purge interval
+func (j *PurgeJob) Start() {
+	log := logger.NewOCMLogger(context.Background())
+
+	if j.retention.PurgeInterval <= 0 {
+		log.Infof("Purge job disabled")
+		return
+	}
+	log.Infof("Purge job running every %s", j.retention.PurgeInterval)
+
+	ticker := time.NewTicker(j.retention.PurgeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		j.Run(context.Background())
+	}
+}
+
+// Run runs every registered purge once, each in its own transaction
+func (j *PurgeJob) Run(ctx context.Context) {
+	log := logger.NewOCMLogger(ctx)
+
+	lockOwnerID, acquired, err := j.lockFactory.NewNonBlockingLock(ctx, "purge", db.Purge)
+	if err != nil {
+		log.Error(fmt.Sprintf("Unable to obtain the purge lock: %s", err))
+		return
+	}
+	if !acquired {
+		log.V(4).Infof("Purge already running on another instance, skipping")
+		return
+	}
+	defer j.lockFactory.Unlock(ctx, lockOwnerID)
+
+	for name, purge := range j.purges {
+		var purged int64
+		err := db.WithTransaction(ctx, env().Database.SessionFactory, func(ctx context.Context) error {
+			var err error
+			purged, err = purge(ctx, j.retention)
+			return err
+		})
+		if err != nil {
+			log.Error(fmt.Sprintf("Unable to purge %s: %s", name, err))
+			continue
+		}
+		if purged > 0 {
+			log.Infof("Purged %d %s", purged, name)
+		}
+	}
+}
@@ -34,6 +34,22 @@ func LoadDiscoveredRoutes(apiV1Router *mux.Router, services ServicesInterface, a
 	}
 }
 
+type MiddlewareRegistrationFunc func(services ServicesInterface) mux.MiddlewareFunc
+
+var middlewareRegistry = make(map[string]MiddlewareRegistrationFunc)
+
+// RegisterMiddleware registers a middleware applied to every API route, within the request transaction
+func RegisterMiddleware(name string, registrationFunc MiddlewareRegistrationFunc) {
+	middlewareRegistry[name] = registrationFunc
+}
+
+func LoadDiscoveredMiddlewares(router *mux.Router, services ServicesInterface) {
+	for name, registrationFunc := range middlewareRegistry {
+		router.Use(registrationFunc(services))
+		_ = name // prevent unused variable warning
+	}
+}
+
 func (s *apiServer) routes() *mux.Router {
 	services := &env().Services
 
@@ -43,7 +59,7 @@ func (s *apiServer) routes() *mux.Router {
 	authMiddleware = &auth.MiddlewareMock{}
 	if env().Config.Server.EnableJWT {
 		var err error
-		authMiddleware, err = auth.NewAuthMiddleware()
+		authMiddleware, err = auth.NewAuthMiddleware(env().Config.Server.RequireOrgID)
 		check(err, "Unable to create auth middleware")
 	}
 	if authMiddleware == nil {
@@ -96,4 +112,7 @@ func registerApiMiddleware(router *mux.Router) {
 	)
 
 	router.Use(gorillahandlers.CompressHandler)
+
+	// Auto-discovered middlewares, within the request transaction
+	LoadDiscoveredMiddlewares(router, &env().Services)
 }
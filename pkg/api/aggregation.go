@@ -0,0 +1,10 @@
+package api
+
+// Aggregation is the aggregates of a group of resources, the ones sharing the values of the Group columns.
+// Count is nil when it wasn't requested, Min and Max hold the requested columns only.
+type Aggregation struct {
+	Group map[string]interface{}
+	Count *int64
+	Min   map[string]interface{}
+	Max   map[string]interface{}
+}
@@ -0,0 +1,44 @@
+package api
+
+import "gorm.io/gorm"
+
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+)
+
+// AuditLog records a mutating API call: who made it, on what, the change it made and how it ended.
+// Before, After and Changes are JSON documents, nil when they don't apply (e.g. no Before for a create).
+type AuditLog struct {
+	Meta
+	Actor        string
+	OperationID  string
+	Method       string
+	Path         string
+	ResourceKind string
+	ResourceID   string
+	Before       *string `gorm:"type:jsonb"`
+	After        *string `gorm:"type:jsonb"`
+	Changes      *string `gorm:"type:jsonb"`
+	StatusCode   int
+	Outcome      AuditOutcome
+	Reason       string
+}
+
+type AuditLogList []*AuditLog
+type AuditLogIndex map[string]*AuditLog
+
+func (l AuditLogList) Index() AuditLogIndex {
+	index := AuditLogIndex{}
+	for _, o := range l {
+		index[o.ID] = o
+	}
+	return index
+}
+
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	a.ID = NewID()
+	return nil
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Labels are the key/value pairs clients tag resources with, stored as a JSONB object.
+// Keys and values follow the Kubernetes syntax, see ValidateLabelKey and ValidateLabelValue.
+type Labels map[string]string
+
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *Labels) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		return fmt.Errorf("unable to scan labels from %T", src)
+	}
+}
+
+const labelNameMaxLength = 63
+
+var (
+	labelNamePattern   = regexp.MustCompile(`^([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]$`)
+	labelPrefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// ValidateLabelKey checks a label key is an optional DNS subdomain prefix and a slash, followed by a name
+// of at most 63 alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric one.
+func ValidateLabelKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if len(prefix) > 253 || !labelPrefixPattern.MatchString(prefix) {
+			return fmt.Errorf("label key '%s' has an invalid prefix, it must be a DNS subdomain", key)
+		}
+	}
+	if len(name) > labelNameMaxLength || !labelNamePattern.MatchString(name) {
+		return fmt.Errorf("label key '%s' is invalid, its name must have at most %d alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character", key, labelNameMaxLength)
+	}
+	return nil
+}
+
+// ValidateLabelValue checks a label value is empty or has the syntax of a label name.
+func ValidateLabelValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > labelNameMaxLength || !labelNamePattern.MatchString(value) {
+		return fmt.Errorf("label value '%s' is invalid, it must have at most %d alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character", value, labelNameMaxLength)
+	}
+	return nil
+}
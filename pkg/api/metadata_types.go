@@ -33,12 +33,16 @@ type Metadata struct {
 	BuildTime string `json:"build_time"`
 }
 
-// Meta is base model definition, embedded in all kinds
+// Meta is base model definition, embedded in all kinds.
+// OrgID is the organization owning the record, the tenant the record is confined to.
+// Labels are the key/value pairs the clients tagged the record with.
 type Meta struct {
 	ID        string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
+	OrgID     string         `gorm:"index"`
+	Labels    Labels         `gorm:"type:jsonb"`
 }
 
 // PagingMeta List Paging metadata
@@ -46,4 +50,6 @@ type PagingMeta struct {
 	Page  int
 	Size  int64
 	Total int64
+	// Continue is the token to list the resources after the last one listed, empty when there are none left
+	Continue string
 }
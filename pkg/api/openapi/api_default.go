@@ -23,13 +23,18 @@ import (
 type DefaultAPIService service
 
 type ApiApiRhTrexV1DinosaursGetRequest struct {
-	ctx        context.Context
-	ApiService *DefaultAPIService
-	page       *int32
-	size       *int32
-	search     *string
-	orderBy    *string
-	fields     *string
+	ctx           context.Context
+	ApiService    *DefaultAPIService
+	page          *int32
+	size          *int32
+	search        *string
+	orderBy       *string
+	fields        *string
+	labelSelector *string
+	continue_     *string
+	total         *string
+	q             *string
+	view          *string
 }
 
 // Page number of record list when record list exceeds specified page size
@@ -62,6 +67,36 @@ func (r ApiApiRhTrexV1DinosaursGetRequest) Fields(fields string) ApiApiRhTrexV1D
 	return r
 }
 
+// Selects the resources by their labels with a Kubernetes style label selector: comma separated requirements that must all be met, such as &#x60;tier&#x3D;gold&#x60;, &#x60;tier!&#x3D;gold&#x60;, &#x60;tier in (gold,silver)&#x60;, &#x60;tier notin (bronze)&#x60;, &#x60;tier&#x60; or &#x60;!tier&#x60;.
+func (r ApiApiRhTrexV1DinosaursGetRequest) LabelSelector(labelSelector string) ApiApiRhTrexV1DinosaursGetRequest {
+	r.labelSelector = &labelSelector
+	return r
+}
+
+// Continues a list after the last resource of the previous page: the &#x60;continue&#x60; token the previous page returned. The list keeps the ordering and the filters of the previous page, and unlike the pages, doesn&#39;t skip nor repeat resources while others are added or removed.
+func (r ApiApiRhTrexV1DinosaursGetRequest) Continue_(continue_ string) ApiApiRhTrexV1DinosaursGetRequest {
+	r.continue_ = &continue_
+	return r
+}
+
+// How the records matching the list are counted: &#x60;exact&#x60; counts them, &#x60;estimate&#x60; estimates their number from the database statistics, which is much cheaper on large tables, and &#x60;none&#x60; doesn&#39;t count them, the list then reporting a total of -1.
+func (r ApiApiRhTrexV1DinosaursGetRequest) Total(total string) ApiApiRhTrexV1DinosaursGetRequest {
+	r.total = &total
+	return r
+}
+
+// Full-text query of the searchable fields, in the web search syntax: the words to match, &quot;quoted phrases&quot;, &#x60;or&#x60; between alternatives and &#x60;-&#x60; before the words to exclude. The results are ordered by relevance unless orderBy is given.
+func (r ApiApiRhTrexV1DinosaursGetRequest) Q(q string) ApiApiRhTrexV1DinosaursGetRequest {
+	r.q = &q
+	return r
+}
+
+// Name of a saved search of the caller for the kind, whose search, orderBy and fields the list uses. The search parameter narrows the one of the saved search, orderBy and fields replace its own.
+func (r ApiApiRhTrexV1DinosaursGetRequest) View(view string) ApiApiRhTrexV1DinosaursGetRequest {
+	r.view = &view
+	return r
+}
+
 func (r ApiApiRhTrexV1DinosaursGetRequest) Execute() (*DinosaurList, *http.Response, error) {
 	return r.ApiService.ApiRhTrexV1DinosaursGetExecute(r)
 }
@@ -122,6 +157,24 @@ func (a *DefaultAPIService) ApiRhTrexV1DinosaursGetExecute(r ApiApiRhTrexV1Dinos
 	if r.fields != nil {
 		parameterAddToHeaderOrQuery(localVarQueryParams, "fields", r.fields, "form", "")
 	}
+	if r.labelSelector != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "labelSelector", r.labelSelector, "form", "")
+	}
+	if r.continue_ != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "continue", r.continue_, "form", "")
+	}
+	if r.total != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "total", r.total, "form", "")
+	} else {
+		var defaultValue string = "exact"
+		r.total = &defaultValue
+	}
+	if r.q != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "q", r.q, "form", "")
+	}
+	if r.view != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "view", r.view, "form", "")
+	}
 	// to determine the Content-Type header
 	localVarHTTPContentTypes := []string{}
 
@@ -22,12 +22,13 @@ var _ MappedNullable = &Dinosaur{}
 
 // Dinosaur struct for Dinosaur
 type Dinosaur struct {
-	Id        *string    `json:"id,omitempty"`
-	Kind      *string    `json:"kind,omitempty"`
-	Href      *string    `json:"href,omitempty"`
-	CreatedAt *time.Time `json:"created_at,omitempty"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty"`
-	Species   string     `json:"species"`
+	Id        *string            `json:"id,omitempty"`
+	Kind      *string            `json:"kind,omitempty"`
+	Href      *string            `json:"href,omitempty"`
+	CreatedAt *time.Time         `json:"created_at,omitempty"`
+	UpdatedAt *time.Time         `json:"updated_at,omitempty"`
+	Species   string             `json:"species"`
+	Labels    *map[string]string `json:"labels,omitempty"`
 }
 
 type _Dinosaur Dinosaur
@@ -234,6 +235,38 @@ func (o *Dinosaur) SetSpecies(v string) {
 	o.Species = v
 }
 
+// GetLabels returns the Labels field value if set, zero value otherwise.
+func (o *Dinosaur) GetLabels() map[string]string {
+	if o == nil || IsNil(o.Labels) {
+		var ret map[string]string
+		return ret
+	}
+	return *o.Labels
+}
+
+// GetLabelsOk returns a tuple with the Labels field value if set, nil otherwise
+// and a boolean to check if the value has been set.
+func (o *Dinosaur) GetLabelsOk() (*map[string]string, bool) {
+	if o == nil || IsNil(o.Labels) {
+		return nil, false
+	}
+	return o.Labels, true
+}
+
+// HasLabels returns a boolean if a field has been set.
+func (o *Dinosaur) HasLabels() bool {
+	if o != nil && !IsNil(o.Labels) {
+		return true
+	}
+
+	return false
+}
+
+// SetLabels gets a reference to the given map[string]string and assigns it to the Labels field.
+func (o *Dinosaur) SetLabels(v map[string]string) {
+	o.Labels = &v
+}
+
 func (o Dinosaur) MarshalJSON() ([]byte, error) {
 	toSerialize, err := o.ToMap()
 	if err != nil {
@@ -260,6 +293,9 @@ func (o Dinosaur) ToMap() (map[string]interface{}, error) {
 		toSerialize["updated_at"] = o.UpdatedAt
 	}
 	toSerialize["species"] = o.Species
+	if !IsNil(o.Labels) {
+		toSerialize["labels"] = o.Labels
+	}
 	return toSerialize, nil
 }
 
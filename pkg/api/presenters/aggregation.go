@@ -0,0 +1,38 @@
+package presenters
+
+import (
+	"github.com/openshift-online/rh-trex/pkg/api"
+)
+
+// Aggregation is the API representation of the aggregates of a group of resources.
+// Aggregations are read only and not part of the generated client.
+type Aggregation struct {
+	Group map[string]interface{} `json:"group"`
+	Count *int64                 `json:"count,omitempty"`
+	Min   map[string]interface{} `json:"min,omitempty"`
+	Max   map[string]interface{} `json:"max,omitempty"`
+}
+
+type AggregationList struct {
+	Kind  string        `json:"kind"`
+	Size  int32         `json:"size"`
+	Items []Aggregation `json:"items"`
+}
+
+// PresentAggregationList presents the aggregations of the resources of the given kind
+func PresentAggregationList(kind string, aggregations []api.Aggregation) AggregationList {
+	aggregationList := AggregationList{
+		Kind:  kind + "AggregationList",
+		Size:  int32(len(aggregations)),
+		Items: []Aggregation{},
+	}
+	for _, aggregation := range aggregations {
+		aggregationList.Items = append(aggregationList.Items, Aggregation{
+			Group: aggregation.Group,
+			Count: aggregation.Count,
+			Min:   aggregation.Min,
+			Max:   aggregation.Max,
+		})
+	}
+	return aggregationList
+}
@@ -0,0 +1,67 @@
+package presenters
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+)
+
+// AuditLog is the API representation of an audit log.
+// Audit logs are read only and not part of the generated client.
+type AuditLog struct {
+	Id           *string         `json:"id,omitempty"`
+	Kind         *string         `json:"kind,omitempty"`
+	Href         *string         `json:"href,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	Actor        string          `json:"actor"`
+	OperationId  string          `json:"operation_id"`
+	Method       string          `json:"method"`
+	Path         string          `json:"path"`
+	ResourceKind string          `json:"resource_kind,omitempty"`
+	ResourceId   string          `json:"resource_id,omitempty"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	Changes      json.RawMessage `json:"changes,omitempty"`
+	StatusCode   int             `json:"status_code"`
+	Outcome      string          `json:"outcome"`
+	Reason       string          `json:"reason,omitempty"`
+}
+
+type AuditLogList struct {
+	Kind     string     `json:"kind"`
+	Page     int32      `json:"page"`
+	Size     int32      `json:"size"`
+	Total    int32      `json:"total"`
+	Continue string     `json:"continue,omitempty"`
+	Items    []AuditLog `json:"items"`
+}
+
+func PresentAuditLog(auditLog *api.AuditLog) AuditLog {
+	reference := PresentReference(auditLog.ID, auditLog)
+	return AuditLog{
+		Id:           reference.Id,
+		Kind:         reference.Kind,
+		Href:         reference.Href,
+		CreatedAt:    auditLog.CreatedAt,
+		Actor:        auditLog.Actor,
+		OperationId:  auditLog.OperationID,
+		Method:       auditLog.Method,
+		Path:         auditLog.Path,
+		ResourceKind: auditLog.ResourceKind,
+		ResourceId:   auditLog.ResourceID,
+		Before:       rawJSON(auditLog.Before),
+		After:        rawJSON(auditLog.After),
+		Changes:      rawJSON(auditLog.Changes),
+		StatusCode:   auditLog.StatusCode,
+		Outcome:      string(auditLog.Outcome),
+		Reason:       auditLog.Reason,
+	}
+}
+
+func rawJSON(doc *string) json.RawMessage {
+	if doc == nil {
+		return nil
+	}
+	return json.RawMessage(*doc)
+}
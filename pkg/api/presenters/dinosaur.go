@@ -9,7 +9,8 @@ import (
 func ConvertDinosaur(dinosaur openapi.Dinosaur) *api.Dinosaur {
 	return &api.Dinosaur{
 		Meta: api.Meta{
-			ID: util.NilToEmptyString(dinosaur.Id),
+			ID:     util.NilToEmptyString(dinosaur.Id),
+			Labels: ConvertLabels(dinosaur.Labels),
 		},
 		Species: dinosaur.Species,
 	}
@@ -22,6 +23,7 @@ func PresentDinosaur(dinosaur *api.Dinosaur) openapi.Dinosaur {
 		Kind:      reference.Kind,
 		Href:      reference.Href,
 		Species:   dinosaur.Species,
+		Labels:    PresentLabels(dinosaur.Labels),
 		CreatedAt: openapi.PtrTime(dinosaur.CreatedAt),
 		UpdatedAt: openapi.PtrTime(dinosaur.UpdatedAt),
 	}
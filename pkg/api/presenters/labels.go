@@ -0,0 +1,26 @@
+package presenters
+
+import (
+	"github.com/openshift-online/rh-trex/pkg/api"
+)
+
+// ConvertLabels returns nil when no labels were given, e.g. to leave the labels of a patched resource as they are
+func ConvertLabels(labels *map[string]string) api.Labels {
+	if labels == nil {
+		return nil
+	}
+	converted := api.Labels{}
+	for key, value := range *labels {
+		converted[key] = value
+	}
+	return converted
+}
+
+// PresentLabels always presents the labels, an empty map when the resource has none
+func PresentLabels(labels api.Labels) *map[string]string {
+	presented := map[string]string{}
+	for key, value := range labels {
+		presented[key] = value
+	}
+	return &presented
+}
@@ -0,0 +1,37 @@
+package presenters
+
+import (
+	"time"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+)
+
+// ResourceVersion is the API representation of a version of a resource,
+// Resource is the resource as it was presented at that time.
+// Versions are read only and not part of the generated client.
+type ResourceVersion struct {
+	Id        string      `json:"id"`
+	Kind      string      `json:"kind"`
+	Operation string      `json:"operation"`
+	ValidFrom time.Time   `json:"valid_from"`
+	ValidTo   *time.Time  `json:"valid_to,omitempty"`
+	Resource  interface{} `json:"resource"`
+}
+
+type ResourceVersionList struct {
+	Kind  string            `json:"kind"`
+	Size  int32             `json:"size"`
+	Items []ResourceVersion `json:"items"`
+}
+
+// PresentResourceVersion presents a version of a resource of the given kind
+func PresentResourceVersion(kind string, version *api.ResourceVersion, resource interface{}) ResourceVersion {
+	return ResourceVersion{
+		Id:        version.ID,
+		Kind:      kind + "Version",
+		Operation: string(version.Operation),
+		ValidFrom: version.ValidFrom,
+		ValidTo:   version.ValidTo,
+		Resource:  resource,
+	}
+}
@@ -0,0 +1,71 @@
+package presenters
+
+import (
+	"time"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/util"
+)
+
+// SavedSearch is the API representation of a saved search.
+// Saved searches are not part of the generated client.
+type SavedSearch struct {
+	Id           *string   `json:"id,omitempty"`
+	Kind         *string   `json:"kind,omitempty"`
+	Href         *string   `json:"href,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Owner        string    `json:"owner,omitempty"`
+	Name         string    `json:"name"`
+	ResourceKind string    `json:"resource_kind"`
+	Search       string    `json:"search,omitempty"`
+	OrderBy      string    `json:"order_by,omitempty"`
+	Fields       string    `json:"fields,omitempty"`
+}
+
+// SavedSearchPatchRequest is the fields of a saved search a patch changes, the ones left out are kept
+type SavedSearchPatchRequest struct {
+	Name         *string `json:"name,omitempty"`
+	ResourceKind *string `json:"resource_kind,omitempty"`
+	Search       *string `json:"search,omitempty"`
+	OrderBy      *string `json:"order_by,omitempty"`
+	Fields       *string `json:"fields,omitempty"`
+}
+
+type SavedSearchList struct {
+	Kind  string        `json:"kind"`
+	Page  int32         `json:"page"`
+	Size  int32         `json:"size"`
+	Total int32         `json:"total"`
+	Items []SavedSearch `json:"items"`
+}
+
+func ConvertSavedSearch(savedSearch SavedSearch) *api.SavedSearch {
+	return &api.SavedSearch{
+		Meta: api.Meta{
+			ID: util.NilToEmptyString(savedSearch.Id),
+		},
+		Name:         savedSearch.Name,
+		ResourceKind: savedSearch.ResourceKind,
+		Search:       savedSearch.Search,
+		OrderBy:      savedSearch.OrderBy,
+		Fields:       savedSearch.Fields,
+	}
+}
+
+func PresentSavedSearch(savedSearch *api.SavedSearch) SavedSearch {
+	reference := PresentReference(savedSearch.ID, savedSearch)
+	return SavedSearch{
+		Id:           reference.Id,
+		Kind:         reference.Kind,
+		Href:         reference.Href,
+		CreatedAt:    savedSearch.CreatedAt,
+		UpdatedAt:    savedSearch.UpdatedAt,
+		Owner:        savedSearch.Owner,
+		Name:         savedSearch.Name,
+		ResourceKind: savedSearch.ResourceKind,
+		Search:       savedSearch.Search,
+		OrderBy:      savedSearch.OrderBy,
+		Fields:       savedSearch.Fields,
+	}
+}
@@ -11,11 +11,12 @@ import (
 )
 
 type ProjectionList struct {
-	Kind  string                   `json:"kind"`
-	Page  int32                    `json:"page"`
-	Size  int32                    `json:"size"`
-	Total int32                    `json:"total"`
-	Items []map[string]interface{} `json:"items"`
+	Kind     string                   `json:"kind"`
+	Page     int32                    `json:"page"`
+	Size     int32                    `json:"size"`
+	Total    int32                    `json:"total"`
+	Continue string                   `json:"continue,omitempty"`
+	Items    []map[string]interface{} `json:"items"`
 }
 
 /*
@@ -54,6 +55,10 @@ func SliceFilter(fields2Store []string, model interface{}) (*ProjectionList, *er
 		Total: int32(reflectValue.FieldByName("Total").Int()),
 		Items: nil,
 	}
+	// the continue tokens are optional, a string or a pointer to one
+	if token := reflect.Indirect(reflectValue.FieldByName("Continue")); token.IsValid() {
+		result.Continue = token.String()
+	}
 
 	field := reflectValue.FieldByName("Items").Interface()
 	items := reflect.ValueOf(field)
@@ -77,6 +82,21 @@ func SliceFilter(fields2Store []string, model interface{}) (*ProjectionList, *er
 	return result, nil
 }
 
+// ItemFilter converts a single structure to a map of the fields to store, as SliceFilter does for each item.
+// Non-existing fields will cause a validation error
+func ItemFilter(fields2Store []string, item interface{}) (map[string]interface{}, *errors.ServiceError) {
+	var in = map[string]bool{}
+	validateIn := map[string]bool{}
+	for _, field := range fields2Store {
+		in[field] = true
+		validateIn[field] = true
+	}
+	if err := validate(item, validateIn, ""); err != nil {
+		return nil, err
+	}
+	return structToMap(item, in, ""), nil
+}
+
 func validate(model interface{}, in map[string]bool, prefix string) *errors.ServiceError {
 	if model == nil {
 		return errors.Validation("Empty model")
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Properties are free form JSON attributes of a resource, stored in a JSONB column.
+// The searches reach them as `properties.<key>`, see db.FieldNameWalk.
+type Properties map[string]interface{}
+
+func (p Properties) Value() (driver.Value, error) {
+	if p == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (p *Properties) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("unable to scan properties from %T", src)
+	}
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ResourceVersion is a state of a resource kept in the history table of its kind:
+// the resource looked like Document from ValidFrom until ValidTo, which is nil for the current version.
+// The version recorded by a delete holds the last state of the resource.
+// Versions belong to the organization owning the resource.
+type ResourceVersion struct {
+	ID         string
+	ResourceID string
+	OrgID      string
+	Operation  EventType
+	ValidFrom  time.Time
+	ValidTo    *time.Time
+	Document   string `gorm:"type:jsonb"`
+}
+
+type ResourceVersionList []*ResourceVersion
+
+func (v *ResourceVersion) BeforeCreate(tx *gorm.DB) error {
+	v.ID = NewID()
+	return nil
+}
+
+// Decode unmarshals the document of the version into resource
+func (v *ResourceVersion) Decode(resource interface{}) error {
+	return json.Unmarshal([]byte(v.Document), resource)
+}
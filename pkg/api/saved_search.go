@@ -0,0 +1,31 @@
+package api
+
+import "gorm.io/gorm"
+
+// SavedSearch is a list query of a kind its owner saved under a name, the lists of the kind use it with the view
+// parameter. OrderBy and Fields are comma separated, as in the list parameters.
+type SavedSearch struct {
+	Meta
+	Owner        string
+	Name         string
+	ResourceKind string
+	Search       string
+	OrderBy      string
+	Fields       string
+}
+
+type SavedSearchList []*SavedSearch
+type SavedSearchIndex map[string]*SavedSearch
+
+func (l SavedSearchList) Index() SavedSearchIndex {
+	index := SavedSearchIndex{}
+	for _, o := range l {
+		index[o.ID] = o
+	}
+	return index
+}
+
+func (s *SavedSearch) BeforeCreate(tx *gorm.DB) error {
+	s.ID = NewID()
+	return nil
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/openshift-online/rh-trex/pkg/errors"
+)
+
+const ContextAdminKey contextKey = "admin"
+
+// AdminMiddleware identifies the administrators among the authenticated users.
+// It must be mounted after the JWT middleware, which stores the username in the request context.
+type AdminMiddleware interface {
+	// IdentifyAdmin flags the request context of administrators, see IsAdmin.
+	IdentifyAdmin(next http.Handler) http.Handler
+	// RequireAdmin rejects the requests of anyone but administrators.
+	RequireAdmin(next http.Handler) http.Handler
+}
+
+type adminMiddleware struct {
+	adminUsers map[string]bool
+}
+
+var _ AdminMiddleware = &adminMiddleware{}
+
+func NewAdminMiddleware(adminUsers []string) AdminMiddleware {
+	m := &adminMiddleware{adminUsers: map[string]bool{}}
+	for _, username := range adminUsers {
+		m.adminUsers[username] = true
+	}
+	return m
+}
+
+func (m *adminMiddleware) IdentifyAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if username := GetUsernameFromContext(ctx); username != "" && m.adminUsers[username] {
+			r = r.WithContext(SetAdminContext(ctx))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *adminMiddleware) RequireAdmin(next http.Handler) http.Handler {
+	return m.IdentifyAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r.Context()) {
+			handleError(r.Context(), w, errors.ErrorForbidden, "Only administrators can perform this action")
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+func SetAdminContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ContextAdminKey, true)
+}
+
+// IsAdmin returns true when the request was made by an administrator.
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(ContextAdminKey).(bool)
+	return admin
+}
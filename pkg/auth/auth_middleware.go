@@ -13,12 +13,16 @@ type JWTMiddleware interface {
 	AuthenticateAccountJWT(next http.Handler) http.Handler
 }
 
-type Middleware struct{}
+type Middleware struct {
+	requireOrgID bool
+}
 
 var _ JWTMiddleware = &Middleware{}
 
-func NewAuthMiddleware() (*Middleware, error) {
-	middleware := Middleware{}
+// NewAuthMiddleware returns the middleware authenticating the callers, requireOrgID rejects the tokens
+// not carrying an organization ID
+func NewAuthMiddleware(requireOrgID bool) (*Middleware, error) {
+	middleware := Middleware{requireOrgID: requireOrgID}
 	return &middleware, nil
 }
 
@@ -32,9 +36,18 @@ func (a *Middleware) AuthenticateAccountJWT(next http.Handler) http.Handler {
 			return
 		}
 
-		// Append the username to the request context
+		// the callers can only reach the records of their organization, the ones without organization share
+		// the records without one, e.g. the records created before the organizations
+		if payload.OrgID == "" && a.requireOrgID {
+			handleError(ctx, w, errors.ErrorForbidden, "JWT token does not carry an organization ID")
+			return
+		}
+
+		// Append the username and the tenant to the request context
 		ctx = SetUsernameContext(ctx, payload.Username)
+		ctx = SetTenantContext(ctx, payload.OrgID)
 		*r = *r.WithContext(ctx)
+		recordCaller(ctx, payload.Username, payload.OrgID)
 
 		// Add username to sentry context
 		if hub := sentry.GetHubFromContext(ctx); hub != nil {
@@ -16,6 +16,8 @@ type contextKey string
 
 const (
 	ContextUsernameKey contextKey = "username"
+	ContextTenantKey   contextKey = "tenant"
+	ContextCallerKey   contextKey = "caller"
 
 	// Does not use contextKey type because the jwt middleware improperly updates context with string key type
 	// See https://github.com/auth0/go-jwt-middleware/blob/master/jwtmiddleware.go#L232
@@ -31,6 +33,7 @@ type Payload struct {
 	Email     string `json:"email"`
 	Issuer    string `json:"iss"`
 	ClientID  string `json:"clientId"`
+	OrgID     string `json:"org_id"`
 }
 
 func SetUsernameContext(ctx context.Context, username string) context.Context {
@@ -45,6 +48,36 @@ func GetUsernameFromContext(ctx context.Context) string {
 	return username.(string)
 }
 
+// SetTenantContext confines the database work done with the returned context to the records
+// of the given organization, see db.RegisterTenantScope.
+func SetTenantContext(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, ContextTenantKey, orgID)
+}
+
+// GetTenantFromContext returns the organization the context is confined to,
+// ok is false when it isn't confined to any, e.g. for controllers and jobs.
+func GetTenantFromContext(ctx context.Context) (orgID string, ok bool) {
+	orgID, ok = ctx.Value(ContextTenantKey).(string)
+	return orgID, ok
+}
+
+// CallerRecorder is told who the caller is once the JWT middleware authenticated it,
+// for the middlewares wrapping it, which don't see the request context it sets.
+type CallerRecorder interface {
+	RecordCaller(username string, orgID string)
+}
+
+// SetCallerRecorderContext returns a context in which the JWT middleware tells the recorder who the caller is.
+func SetCallerRecorderContext(ctx context.Context, recorder CallerRecorder) context.Context {
+	return context.WithValue(ctx, ContextCallerKey, recorder)
+}
+
+func recordCaller(ctx context.Context, username string, orgID string) {
+	if recorder, ok := ctx.Value(ContextCallerKey).(CallerRecorder); ok {
+		recorder.RecordCaller(username, orgID)
+	}
+}
+
 // GetAuthPayloadFromContext Get authorization payload api object from context
 func GetAuthPayloadFromContext(ctx context.Context) (*Payload, error) {
 	// Get user token from request context and validate
@@ -81,12 +114,20 @@ func GetAuthPayloadFromContext(ctx context.Context) (*Payload, error) {
 	payload.LastName, _ = claims["last_name"].(string)
 	payload.Email, _ = claims["email"].(string)
 	payload.ClientID, _ = claims["clientId"].(string)
+	payload.OrgID, _ = claims["org_id"].(string)
 
 	// Check values, if empty, use alternative claims from RHD
 	if payload.Username == "" {
 		payload.Username, _ = claims["preferred_username"].(string)
 	}
 
+	// OCM accounts carry their organization as a claim object
+	if payload.OrgID == "" {
+		if organization, ok := claims["organization"].(map[string]interface{}); ok {
+			payload.OrgID, _ = organization["id"].(string)
+		}
+	}
+
 	if payload.FirstName == "" {
 		payload.FirstName, _ = claims["given_name"].(string)
 	}
@@ -19,6 +19,8 @@ type ApplicationConfig struct {
 	Database    *DatabaseConfig    `json:"database"`
 	OCM         *OCMConfig         `json:"ocm"`
 	Sentry      *SentryConfig      `json:"sentry"`
+	Retention   *RetentionConfig   `json:"retention"`
+	QueryLimits *QueryLimitsConfig `json:"query_limits"`
 }
 
 func NewApplicationConfig() *ApplicationConfig {
@@ -29,6 +31,8 @@ func NewApplicationConfig() *ApplicationConfig {
 		Database:    NewDatabaseConfig(),
 		OCM:         NewOCMConfig(),
 		Sentry:      NewSentryConfig(),
+		Retention:   NewRetentionConfig(),
+		QueryLimits: NewQueryLimitsConfig(),
 	}
 }
 
@@ -40,6 +44,8 @@ func (c *ApplicationConfig) AddFlags(flagset *pflag.FlagSet) {
 	c.Database.AddFlags(flagset)
 	c.OCM.AddFlags(flagset)
 	c.Sentry.AddFlags(flagset)
+	c.Retention.AddFlags(flagset)
+	c.QueryLimits.AddFlags(flagset)
 }
 
 func (c *ApplicationConfig) ReadFiles() []string {
@@ -53,6 +59,8 @@ func (c *ApplicationConfig) ReadFiles() []string {
 		{c.Metrics.ReadFiles, "Metrics"},
 		{c.HealthCheck.ReadFiles, "HealthCheck"},
 		{c.Sentry.ReadFiles, "Sentry"},
+		{c.Retention.ReadFiles, "Retention"},
+		{c.QueryLimits.ReadFiles, "QueryLimits"},
 	}
 	var messages []string
 	for _, rf := range readFiles {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -11,6 +12,17 @@ type DatabaseConfig struct {
 	SSLMode            string `json:"sslmode"`
 	Debug              bool   `json:"debug"`
 	MaxOpenConnections int    `json:"max_connections"`
+	MaxIdleConnections int    `json:"max_idle_connections"`
+
+	ConnMaxLifetime  time.Duration `json:"conn_max_lifetime"`
+	ConnMaxIdleTime  time.Duration `json:"conn_max_idle_time"`
+	StatementTimeout time.Duration `json:"statement_timeout"`
+	LockTimeout      time.Duration `json:"lock_timeout"`
+
+	AdvisoryLockTimeout    time.Duration `json:"advisory_lock_timeout"`
+	AdvisoryLockLegacyKeys bool          `json:"advisory_lock_legacy_keys"`
+
+	TransactionRetries int `json:"transaction_retries"`
 
 	Host     string `json:"host"`
 	Port     int    `json:"port"`
@@ -32,6 +44,16 @@ func NewDatabaseConfig() *DatabaseConfig {
 		SSLMode:            "disable",
 		Debug:              false,
 		MaxOpenConnections: 50,
+		MaxIdleConnections: 10,
+
+		ConnMaxLifetime:  30 * time.Minute,
+		ConnMaxIdleTime:  5 * time.Minute,
+		StatementTimeout: 0,
+		LockTimeout:      0,
+
+		AdvisoryLockTimeout: 10 * time.Second,
+
+		TransactionRetries: 3,
 
 		HostFile:     "secrets/db.host",
 		PortFile:     "secrets/db.port",
@@ -52,6 +74,14 @@ func (c *DatabaseConfig) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&c.SSLMode, "db-sslmode", c.SSLMode, "Database ssl mode (disable | require | verify-ca | verify-full)")
 	fs.BoolVar(&c.Debug, "enable-db-debug", c.Debug, " framework's debug mode")
 	fs.IntVar(&c.MaxOpenConnections, "db-max-open-connections", c.MaxOpenConnections, "Maximum open DB connections for this instance")
+	fs.IntVar(&c.MaxIdleConnections, "db-max-idle-connections", c.MaxIdleConnections, "Maximum idle DB connections kept in the pool for this instance")
+	fs.DurationVar(&c.ConnMaxLifetime, "db-conn-max-lifetime", c.ConnMaxLifetime, "Maximum amount of time a DB connection may be reused, 0 to reuse forever")
+	fs.DurationVar(&c.ConnMaxIdleTime, "db-conn-max-idle-time", c.ConnMaxIdleTime, "Maximum amount of time a DB connection may be idle before being closed, 0 to keep forever")
+	fs.DurationVar(&c.StatementTimeout, "db-statement-timeout", c.StatementTimeout, "Postgres statement_timeout set on the DB sessions serving the API, 0 to disable")
+	fs.DurationVar(&c.LockTimeout, "db-lock-timeout", c.LockTimeout, "Postgres lock_timeout set on the DB sessions serving the API, 0 to disable")
+	fs.DurationVar(&c.AdvisoryLockTimeout, "db-advisory-lock-timeout", c.AdvisoryLockTimeout, "Maximum time to wait for a blocking advisory lock, 0 to wait until the request context is done")
+	fs.BoolVar(&c.AdvisoryLockLegacyKeys, "db-advisory-lock-legacy-keys", c.AdvisoryLockLegacyKeys, "Use the 32-bit (id, type) advisory lock keys of previous releases, enable while pods of those releases are still running")
+	fs.IntVar(&c.TransactionRetries, "db-transaction-retries", c.TransactionRetries, "Maximum attempts of a transaction failing on a serialization failure or deadlock, for the routes creating, updating and deleting resources")
 }
 
 func (c *DatabaseConfig) ReadFiles() error {
@@ -100,6 +130,34 @@ func (c *DatabaseConfig) ConnectionStringWithName(name string, withSSL bool) str
 	return cmd
 }
 
+// PoolConnectionString is the connection string of the pool serving the API, see SessionParameters.
+func (c *DatabaseConfig) PoolConnectionString(withSSL bool) string {
+	return c.PoolConnectionStringWithName(c.Name, withSSL)
+}
+
+func (c *DatabaseConfig) PoolConnectionStringWithName(name string, withSSL bool) string {
+	cmd := c.ConnectionStringWithName(name, withSSL)
+	for _, param := range c.SessionParameters() {
+		cmd += " " + param
+	}
+	return cmd
+}
+
+// SessionParameters returns the run-time parameters set on every session opened by the pool serving the API,
+// as "key=value" pairs. lib/pq sends any connection string key it does not recognize to the
+// server as a run-time parameter, so these apply before the first statement runs.
+// The migrations and the listeners connect without them, a long migration or an idle LISTEN must not time out.
+func (c *DatabaseConfig) SessionParameters() []string {
+	var params []string
+	if c.StatementTimeout > 0 {
+		params = append(params, fmt.Sprintf("statement_timeout=%d", c.StatementTimeout.Milliseconds()))
+	}
+	if c.LockTimeout > 0 {
+		params = append(params, fmt.Sprintf("lock_timeout=%d", c.LockTimeout.Milliseconds()))
+	}
+	return params
+}
+
 func (c *DatabaseConfig) LogSafeConnectionString(withSSL bool) string {
 	return c.LogSafeConnectionStringWithName(c.Name, withSSL)
 }
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDatabaseSessionParameters(t *testing.T) {
+	RegisterTestingT(t)
+
+	c := NewDatabaseConfig()
+	Expect(c.SessionParameters()).To(BeEmpty())
+	Expect(c.ConnectionString(false)).NotTo(ContainSubstring("timeout"))
+
+	c.StatementTimeout = 30 * time.Second
+	c.LockTimeout = 500 * time.Millisecond
+	Expect(c.SessionParameters()).To(Equal([]string{"statement_timeout=30000", "lock_timeout=500"}))
+	Expect(c.PoolConnectionString(false)).To(HaveSuffix("sslmode=disable statement_timeout=30000 lock_timeout=500"))
+	// the migrations and the listeners connect without them
+	Expect(c.ConnectionString(false)).NotTo(ContainSubstring("timeout"))
+}
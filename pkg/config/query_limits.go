@@ -0,0 +1,39 @@
+package config
+
+import (
+	"github.com/spf13/pflag"
+)
+
+// QueryLimitsConfig bounds the complexity of the queries of the lists, the lists exceeding a limit are rejected.
+// A limit of 0 disables it, they are all disabled by default so that the searches of the existing clients keep working.
+//
+//	MaxSearchNodes is the number of nodes of the search: its comparisons, their fields, values and in lists, and the and/or/not joining them.
+//	MaxJoins is the number of related kinds a search joins.
+//	MaxInListLength is the number of values of an in or not in list.
+//	MaxPlanCost is the cost of the query plan of the list, as estimated by EXPLAIN, which is only run when it is set.
+type QueryLimitsConfig struct {
+	MaxSearchNodes  int     `json:"max_search_nodes"`
+	MaxJoins        int     `json:"max_joins"`
+	MaxInListLength int     `json:"max_in_list_length"`
+	MaxPlanCost     float64 `json:"max_plan_cost"`
+}
+
+func NewQueryLimitsConfig() *QueryLimitsConfig {
+	return &QueryLimitsConfig{
+		MaxSearchNodes:  0,
+		MaxJoins:        0,
+		MaxInListLength: 0,
+		MaxPlanCost:     0,
+	}
+}
+
+func (c *QueryLimitsConfig) AddFlags(fs *pflag.FlagSet) {
+	fs.IntVar(&c.MaxSearchNodes, "query-max-search-nodes", c.MaxSearchNodes, "Maximum number of nodes of the search of a list, 0 for no limit")
+	fs.IntVar(&c.MaxJoins, "query-max-joins", c.MaxJoins, "Maximum number of related kinds the search of a list joins, 0 for no limit")
+	fs.IntVar(&c.MaxInListLength, "query-max-in-list-length", c.MaxInListLength, "Maximum number of values of an in list of a search, 0 for no limit")
+	fs.Float64Var(&c.MaxPlanCost, "query-max-plan-cost", c.MaxPlanCost, "Maximum cost of the query plan of a list as estimated by EXPLAIN, 0 to not explain the lists")
+}
+
+func (c *QueryLimitsConfig) ReadFiles() error {
+	return nil
+}
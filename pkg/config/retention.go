@@ -0,0 +1,33 @@
+package config
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// RetentionConfig defines how long records are kept before the purge job removes them permanently.
+// A retention of 0 keeps the records forever.
+type RetentionConfig struct {
+	SoftDeleted   time.Duration `json:"soft_deleted"`
+	AuditLogs     time.Duration `json:"audit_logs"`
+	PurgeInterval time.Duration `json:"purge_interval"`
+}
+
+func NewRetentionConfig() *RetentionConfig {
+	return &RetentionConfig{
+		SoftDeleted:   0,
+		AuditLogs:     0,
+		PurgeInterval: time.Hour,
+	}
+}
+
+func (c *RetentionConfig) AddFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&c.SoftDeleted, "retention-soft-deleted", c.SoftDeleted, "Time soft-deleted resources are kept before being purged, 0 to keep them forever")
+	fs.DurationVar(&c.AuditLogs, "retention-audit-logs", c.AuditLogs, "Time audit logs are kept before being purged, 0 to keep them forever")
+	fs.DurationVar(&c.PurgeInterval, "retention-purge-interval", c.PurgeInterval, "Interval between runs of the purge job")
+}
+
+func (c *RetentionConfig) ReadFiles() error {
+	return nil
+}
@@ -19,6 +19,8 @@ type ServerConfig struct {
 	JwkCertFile   string        `json:"jwk_cert_file"`
 	JwkCertURL    string        `json:"jwk_cert_url"`
 	ACLFile       string        `json:"acl_file"`
+	AdminUsers    []string      `json:"admin_users"`
+	RequireOrgID  bool          `json:"require_org_id"`
 }
 
 func NewServerConfig() *ServerConfig {
@@ -35,6 +37,7 @@ func NewServerConfig() *ServerConfig {
 		ACLFile:       "",
 		HTTPSCertFile: "",
 		HTTPSKeyFile:  "",
+		RequireOrgID:  false,
 	}
 }
 
@@ -51,6 +54,8 @@ func (s *ServerConfig) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.JwkCertFile, "jwk-cert-file", s.JwkCertFile, "JWK Certificate file")
 	fs.StringVar(&s.JwkCertURL, "jwk-cert-url", s.JwkCertURL, "JWK Certificate URL")
 	fs.StringVar(&s.ACLFile, "acl-file", s.ACLFile, "Access control list file")
+	fs.StringSliceVar(&s.AdminUsers, "admin-users", s.AdminUsers, "Usernames allowed to use the admin endpoints")
+	fs.BoolVar(&s.RequireOrgID, "require-org-id", s.RequireOrgID, "Reject the JWT tokens not carrying an organization ID, otherwise their callers share the records without organization")
 }
 
 func (s *ServerConfig) ReadFiles() error {
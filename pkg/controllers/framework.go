@@ -39,16 +39,21 @@ type ControllerConfig struct {
 }
 
 type KindControllerManager struct {
-	controllers map[string]map[api.EventType][]ControllerHandlerFunc
-	lockFactory db.LockFactory
-	events      services.EventService
+	controllers    map[string]map[api.EventType][]ControllerHandlerFunc
+	lockFactory    db.LockFactory
+	events         services.EventService
+	sessionFactory db.SessionFactory
 }
 
-func NewKindControllerManager(lockFactory db.LockFactory, events services.EventService) *KindControllerManager {
+// NewKindControllerManager returns a manager dispatching events to the controllers added to it.
+// Each event is handled in a transaction of sessionFactory, so the side effects of its handlers and
+// the ReconciledDate of the event are committed together. A nil sessionFactory handles events without a transaction.
+func NewKindControllerManager(lockFactory db.LockFactory, events services.EventService, sessionFactory db.SessionFactory) *KindControllerManager {
 	return &KindControllerManager{
-		controllers: map[string]map[api.EventType][]ControllerHandlerFunc{},
-		lockFactory: lockFactory,
-		events:      events,
+		controllers:    map[string]map[api.EventType][]ControllerHandlerFunc{},
+		lockFactory:    lockFactory,
+		events:         events,
+		sessionFactory: sessionFactory,
 	}
 }
 
@@ -100,31 +105,42 @@ func (km *KindControllerManager) handle(ctx context.Context, id string) {
 
 	log := logger.NewOCMLogger(ctx)
 
+	err := db.WithTransaction(ctx, km.sessionFactory, func(ctx context.Context) error {
+		return km.dispatch(ctx, id)
+	})
+	if err != nil {
+		log.Error(err.Error())
+	}
+}
+
+// dispatch runs the handlers of the event and marks it reconciled once all of them succeeded.
+// an error rolls back whatever the handlers did so the event is processed again on the next sync.
+func (km *KindControllerManager) dispatch(ctx context.Context, id string) error {
+
+	log := logger.NewOCMLogger(ctx)
+
 	event, err := km.events.Get(ctx, id)
 
 	if err != nil {
-		log.Error(err.Error())
-		return
+		return err
 	}
 
 	source, found := km.controllers[event.Source]
 	if !found {
 		log.Infof("No controllers found for '%s'\n", event.Source)
-		return
+		return nil
 	}
 
 	handlerFns, found := source[event.EventType]
 	if !found {
 		log.Infof("No handler functions found for '%s-%s'\n", event.Source, event.EventType)
-		return
+		return nil
 	}
 
 	for _, fn := range handlerFns {
 		err := fn(ctx, event.SourceID)
 		if err != nil {
-			errStr := fmt.Sprintf("error handing event %s, %s, %s: %s", event.Source, event.EventType, id, err)
-			log.Error(errStr)
-			return
+			return fmt.Errorf("error handing event %s, %s, %s: %s", event.Source, event.EventType, id, err)
 		}
 	}
 
@@ -133,6 +149,7 @@ func (km *KindControllerManager) handle(ctx context.Context, id string) {
 	event.ReconciledDate = &now
 	_, err = km.events.Replace(ctx, event)
 	if err != nil {
-		log.Error(err.Error())
+		return err
 	}
+	return nil
 }
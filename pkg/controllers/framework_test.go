@@ -49,7 +49,7 @@ func TestControllerFramework(t *testing.T) {
 	ctx := context.Background()
 	eventsDao := mocks.NewEventDao()
 	events := services.NewEventService(eventsDao)
-	mgr := NewKindControllerManager(dbmocks.NewMockAdvisoryLockFactory(), events)
+	mgr := NewKindControllerManager(dbmocks.NewMockAdvisoryLockFactory(), events, nil)
 
 	ctrl := &exampleController{}
 	config := newExampleControllerConfig(ctrl)
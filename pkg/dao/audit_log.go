@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/db"
+)
+
+type AuditLogDao interface {
+	Get(ctx context.Context, id string) (*api.AuditLog, error)
+	Create(ctx context.Context, auditLog *api.AuditLog) (*api.AuditLog, error)
+	PurgeBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+var _ AuditLogDao = &sqlAuditLogDao{}
+
+type sqlAuditLogDao struct {
+	sessionFactory *db.SessionFactory
+}
+
+func NewAuditLogDao(sessionFactory *db.SessionFactory) AuditLogDao {
+	return &sqlAuditLogDao{sessionFactory: sessionFactory}
+}
+
+func (d *sqlAuditLogDao) Get(ctx context.Context, id string) (*api.AuditLog, error) {
+	g2 := (*d.sessionFactory).New(ctx)
+	var auditLog api.AuditLog
+	if err := g2.Take(&auditLog, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &auditLog, nil
+}
+
+func (d *sqlAuditLogDao) Create(ctx context.Context, auditLog *api.AuditLog) (*api.AuditLog, error) {
+	g2 := (*d.sessionFactory).New(ctx)
+	if err := g2.Omit(clause.Associations).Create(auditLog).Error; err != nil {
+		db.MarkForRollback(ctx, err)
+		return nil, err
+	}
+	return auditLog, nil
+}
+
+// PurgeBefore permanently deletes the audit logs created before the given time and returns how many were deleted.
+func (d *sqlAuditLogDao) PurgeBefore(ctx context.Context, before time.Time) (int64, error) {
+	g2 := (*d.sessionFactory).New(ctx)
+	result := g2.Unscoped().Where("created_at < ?", before).Delete(&api.AuditLog{})
+	if result.Error != nil {
+		db.MarkForRollback(ctx, result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -2,7 +2,10 @@ package dao
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
 	"github.com/openshift-online/rh-trex/pkg/api"
@@ -14,6 +17,11 @@ type DinosaurDao interface {
 	Create(ctx context.Context, dinosaur *api.Dinosaur) (*api.Dinosaur, error)
 	Replace(ctx context.Context, dinosaur *api.Dinosaur) (*api.Dinosaur, error)
 	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, id string) (bool, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	History(ctx context.Context, id string) (api.ResourceVersionList, error)
+	GetAsOf(ctx context.Context, id string, asOf time.Time) (*api.Dinosaur, error)
 	FindByIDs(ctx context.Context, ids []string) (api.DinosaurList, error)
 	FindBySpecies(ctx context.Context, species string) (api.DinosaurList, error)
 	All(ctx context.Context) (api.DinosaurList, error)
@@ -21,6 +29,9 @@ type DinosaurDao interface {
 
 var _ DinosaurDao = &sqlDinosaurDao{}
 
+// dinosaurHistoryTable keeps the versions of the dinosaurs
+const dinosaurHistoryTable = "dinosaur_versions"
+
 type sqlDinosaurDao struct {
 	sessionFactory *db.SessionFactory
 }
@@ -44,6 +55,10 @@ func (d *sqlDinosaurDao) Create(ctx context.Context, dinosaur *api.Dinosaur) (*a
 		db.MarkForRollback(ctx, err)
 		return nil, err
 	}
+	if err := recordVersion(g2, dinosaurHistoryTable, dinosaur.ID, dinosaur.OrgID, api.CreateEventType, dinosaur); err != nil {
+		db.MarkForRollback(ctx, err)
+		return nil, err
+	}
 	return dinosaur, nil
 }
 
@@ -53,18 +68,113 @@ func (d *sqlDinosaurDao) Replace(ctx context.Context, dinosaur *api.Dinosaur) (*
 		db.MarkForRollback(ctx, err)
 		return nil, err
 	}
+	if err := recordVersion(g2, dinosaurHistoryTable, dinosaur.ID, dinosaur.OrgID, api.UpdateEventType, dinosaur); err != nil {
+		db.MarkForRollback(ctx, err)
+		return nil, err
+	}
 	return dinosaur, nil
 }
 
 func (d *sqlDinosaurDao) Delete(ctx context.Context, id string) error {
 	g2 := (*d.sessionFactory).New(ctx)
-	if err := g2.Omit(clause.Associations).Delete(&api.Dinosaur{Meta: api.Meta{ID: id}}).Error; err != nil {
+	var dinosaur api.Dinosaur
+	if err := g2.Take(&dinosaur, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// deleting a missing dinosaur is not an error
+			return nil
+		}
+		return err
+	}
+	if err := g2.Omit(clause.Associations).Delete(&dinosaur).Error; err != nil {
+		db.MarkForRollback(ctx, err)
+		return err
+	}
+	if err := recordVersion(g2, dinosaurHistoryTable, id, dinosaur.OrgID, api.DeleteEventType, &dinosaur); err != nil {
+		db.MarkForRollback(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// Restore undeletes a soft-deleted dinosaur, it returns gorm.ErrRecordNotFound when there is none with this id.
+func (d *sqlDinosaurDao) Restore(ctx context.Context, id string) error {
+	g2 := (*d.sessionFactory).New(ctx)
+	result := g2.Unscoped().Model(&api.Dinosaur{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now()})
+	if result.Error != nil {
+		db.MarkForRollback(ctx, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	var dinosaur api.Dinosaur
+	if err := g2.Take(&dinosaur, "id = ?", id).Error; err != nil {
+		db.MarkForRollback(ctx, err)
+		return err
+	}
+	if err := recordVersion(g2, dinosaurHistoryTable, id, dinosaur.OrgID, api.UpdateEventType, &dinosaur); err != nil {
 		db.MarkForRollback(ctx, err)
 		return err
 	}
 	return nil
 }
 
+// History returns the versions of a dinosaur, oldest first. The versions outlive purged dinosaurs.
+func (d *sqlDinosaurDao) History(ctx context.Context, id string) (api.ResourceVersionList, error) {
+	g2 := (*d.sessionFactory).New(ctx)
+	return findVersions(g2, dinosaurHistoryTable, id)
+}
+
+// GetAsOf returns the dinosaur as it was at the given time,
+// it returns gorm.ErrRecordNotFound when it didn't exist at that time.
+func (d *sqlDinosaurDao) GetAsOf(ctx context.Context, id string, asOf time.Time) (*api.Dinosaur, error) {
+	g2 := (*d.sessionFactory).New(ctx)
+	version, err := findVersionAsOf(g2, dinosaurHistoryTable, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+	var dinosaur api.Dinosaur
+	if err := version.Decode(&dinosaur); err != nil {
+		return nil, err
+	}
+	return &dinosaur, nil
+}
+
+// Purge permanently deletes a dinosaur, soft-deleted or not, and reports whether it was soft-deleted.
+// it returns gorm.ErrRecordNotFound when there is no dinosaur with this id.
+func (d *sqlDinosaurDao) Purge(ctx context.Context, id string) (bool, error) {
+	g2 := (*d.sessionFactory).New(ctx)
+	var dinosaur api.Dinosaur
+	if err := g2.Unscoped().Take(&dinosaur, "id = ?", id).Error; err != nil {
+		return false, err
+	}
+	if err := g2.Unscoped().Omit(clause.Associations).Delete(&dinosaur).Error; err != nil {
+		db.MarkForRollback(ctx, err)
+		return false, err
+	}
+	if !dinosaur.DeletedAt.Valid {
+		// the deletion of a soft-deleted dinosaur is already recorded
+		if err := recordVersion(g2, dinosaurHistoryTable, id, dinosaur.OrgID, api.DeleteEventType, &dinosaur); err != nil {
+			db.MarkForRollback(ctx, err)
+			return false, err
+		}
+	}
+	return dinosaur.DeletedAt.Valid, nil
+}
+
+// PurgeDeleted permanently deletes the dinosaurs soft-deleted before the given time and returns how many were deleted.
+func (d *sqlDinosaurDao) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	g2 := (*d.sessionFactory).New(ctx)
+	result := g2.Unscoped().Where("deleted_at < ?", before).Delete(&api.Dinosaur{})
+	if result.Error != nil {
+		db.MarkForRollback(ctx, result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
 func (d *sqlDinosaurDao) FindByIDs(ctx context.Context, ids []string) (api.DinosaurList, error) {
 	g2 := (*d.sessionFactory).New(ctx)
 	dinosaurs := api.DinosaurList{}
@@ -2,10 +2,13 @@ package dao
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/jinzhu/inflection"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 
 	"github.com/openshift-online/rh-trex/pkg/db"
 )
@@ -27,15 +30,23 @@ type GenericDao interface {
 
 	GetInstanceDao(ctx context.Context, model interface{}) GenericDao
 	Preload(preload string)
+	Select(columns []string)
 	OrderBy(orderBy string)
-	Joins(sql string)
+	Joins(sql string, values ...any)
 	Group(sql string)
 	Where(where Where)
+	Unscoped()
 	Count(model interface{}, total *int64)
+	EstimateCount(model interface{}, total *int64) error
+	ExplainCost(model interface{}, offset int, limit int, cost *float64) error
+	Aggregate(columns []string, aggregates []string, rows *[]map[string]interface{}) error
+	Stream(newResource func() interface{}, each func(resource interface{}) error) error
+	Take(resource interface{}) error
 	Validate(resourceList interface{}) error
 
 	GetTableName() string
 	GetTableRelation(fieldName string) (TableRelation, bool)
+	GetColumnField(column string) (*schema.Field, bool)
 }
 
 var _ GenericDao = &sqlGenericDao{}
@@ -52,6 +63,8 @@ type TableRelation struct {
 	ColumnName        string
 	ForeignTableName  string
 	ForeignColumnName string
+	// ForeignTenantColumn holds the organization owning the foreign records, empty when they belong to none
+	ForeignTenantColumn string
 }
 
 func NewGenericDao(sessionFactory *db.SessionFactory) GenericDao {
@@ -73,12 +86,16 @@ func (d *sqlGenericDao) Preload(preload string) {
 	d.g2 = d.g2.Preload(preload)
 }
 
+func (d *sqlGenericDao) Select(columns []string) {
+	d.g2 = d.g2.Select(columns)
+}
+
 func (d *sqlGenericDao) OrderBy(orderBy string) {
 	d.g2 = d.g2.Order(orderBy)
 }
 
-func (d *sqlGenericDao) Joins(sql string) {
-	d.g2 = d.g2.Joins(sql)
+func (d *sqlGenericDao) Joins(sql string, values ...any) {
+	d.g2 = d.g2.Joins(sql, values...)
 }
 
 func (d *sqlGenericDao) Group(sql string) {
@@ -89,6 +106,11 @@ func (d *sqlGenericDao) Where(where Where) {
 	d.g2 = d.g2.Where(where.sql, where.values...)
 }
 
+// Unscoped includes soft-deleted records
+func (d *sqlGenericDao) Unscoped() {
+	d.g2 = d.g2.Unscoped()
+}
+
 func (d *sqlGenericDao) Count(model interface{}, total *int64) {
 	// Creates new session which already clears all statement clauses
 	g2 := d.g2.Session(&gorm.Session{DryRun: false}).Model(model)
@@ -102,6 +124,104 @@ func (d *sqlGenericDao) Count(model interface{}, total *int64) {
 	g2.Count(total)
 }
 
+// EstimateCount estimates the number of records from the planner statistics instead of counting them: the rows of
+// the table for the unfiltered lists, the rows the planner expects the query to return otherwise
+func (d *sqlGenericDao) EstimateCount(model interface{}, total *int64) error {
+	_, confined := db.ConfiningTenant(d.g2.Statement.Context)
+	if _, filtered := d.g2.Statement.Clauses["WHERE"]; !filtered && !confined && len(d.g2.Statement.Joins) == 0 {
+		var reltuples int64
+		err := d.g2.Session(&gorm.Session{}).
+			Raw("SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass(?)", d.GetTableName()).
+			Row().Scan(&reltuples)
+		if err != nil {
+			return err
+		}
+		// the tables never analyzed have no statistics yet
+		if reltuples >= 0 {
+			*total = reltuples
+			return nil
+		}
+	}
+
+	plan, err := d.explain(model, 0, 0)
+	if err != nil {
+		return err
+	}
+	*total = int64(plan.Rows)
+	return nil
+}
+
+// ExplainCost estimates the cost of the query of the page of the records Fetch would load, in the units of the planner,
+// without running it. A limit of 0 explains the query of all the records.
+func (d *sqlGenericDao) ExplainCost(model interface{}, offset int, limit int, cost *float64) error {
+	plan, err := d.explain(model, offset, limit)
+	if err != nil {
+		return err
+	}
+	*cost = plan.TotalCost
+	return nil
+}
+
+// queryPlan is the top node of the plan EXPLAIN returns
+type queryPlan struct {
+	Rows      float64 `json:"Plan Rows"`
+	TotalCost float64 `json:"Total Cost"`
+}
+
+// explain returns the plan of the query of the page of the records, all of them when limit is 0, considering the joins
+// and the search params as Count does
+func (d *sqlGenericDao) explain(model interface{}, offset int, limit int) (*queryPlan, error) {
+	stmt := d.g2.Session(&gorm.Session{DryRun: true, WithConditions: true}).Offset(offset).Limit(limit).Find(model).Statement
+	var plan []struct {
+		Plan queryPlan `json:"Plan"`
+	}
+	var explained string
+	row := stmt.ConnPool.QueryRowContext(stmt.Context, "EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...)
+	if err := row.Scan(&explained); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(explained), &plan); err != nil || len(plan) == 0 {
+		return nil, fmt.Errorf("unable to read the query plan: %s", explained)
+	}
+	return &plan[0].Plan, nil
+}
+
+// Stream loads the records one at a time from a cursor, each into a new resource which is passed to each,
+// for the lists too large to be loaded at once. It stops at the first error of each.
+func (d *sqlGenericDao) Stream(newResource func() interface{}, each func(resource interface{}) error) error {
+	rows, err := d.g2.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		resource := newResource()
+		if err := d.g2.ScanRows(rows, resource); err != nil {
+			return err
+		}
+		if err := each(resource); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Aggregate loads the aggregates of the records grouped by the columns, one row per group ordered by the
+// columns, or a single row when there are no columns. The rows are keyed by the names of the selected columns
+// and of the aliases of the aggregates.
+func (d *sqlGenericDao) Aggregate(columns []string, aggregates []string, rows *[]map[string]interface{}) error {
+	g2 := d.g2.Select(append(append([]string{}, columns...), aggregates...))
+	if len(columns) > 0 {
+		g2 = g2.Group(strings.Join(columns, ",")).Order(strings.Join(columns, ","))
+	}
+	return g2.Find(rows).Error
+}
+
+// Take loads the record of the resource, the one of its primary key, with the preloads
+func (d *sqlGenericDao) Take(resource interface{}) error {
+	return d.g2.Take(resource).Error
+}
+
 // Gorm finishers (Take, First, Last, etc.) are not idempotent
 // Use a new session to execute these checks
 func (d *sqlGenericDao) Validate(resourceList interface{}) error {
@@ -115,10 +235,20 @@ func (d *sqlGenericDao) GetTableName() string {
 	return db.GetTableName(d.g2)
 }
 
-// extract the relation from the api model
+// GetColumnField returns the field of the api model stored in the column
+func (d *sqlGenericDao) GetColumnField(column string) (*schema.Field, bool) {
+	if d.g2.Statement.Parse(d.g2.Statement.Model) != nil {
+		return nil, false
+	}
+	field, ok := d.g2.Statement.Schema.FieldsByDBName[column]
+	return field, ok
+}
+
+// extract the relation from the api model, the field of the relation being named as the relation in camel case,
+// e.g. FossilSite for fossil_site
 func (d *sqlGenericDao) GetTableRelation(fieldName string) (TableRelation, bool) {
 	// try singular
-	fieldName = strings.ToUpper(fieldName[:1]) + fieldName[1:]
+	fieldName = camelCase(fieldName)
 	table := inflection.Singular(fieldName)
 	association := d.g2.Association(table)
 	// the relation must exist in the model
@@ -143,10 +273,23 @@ func (d *sqlGenericDao) GetTableRelation(fieldName string) (TableRelation, bool)
 		foreignColumnName = association.Relationship.References[0].ForeignKey.DBName
 	}
 
+	foreignTenantColumn, _ := db.TenantColumn(association.Relationship.FieldSchema)
 	return TableRelation{
-		TableName:         association.Relationship.Field.Schema.Table,
-		ForeignTableName:  association.Relationship.FieldSchema.Table,
-		ForeignColumnName: foreignColumnName,
-		ColumnName:        columnName,
+		TableName:           association.Relationship.Field.Schema.Table,
+		ForeignTableName:    association.Relationship.FieldSchema.Table,
+		ForeignColumnName:   foreignColumnName,
+		ColumnName:          columnName,
+		ForeignTenantColumn: foreignTenantColumn,
 	}, true
 }
+
+// camelCase upper-cases the first letter of each of the snake case words of the name and joins them
+func camelCase(name string) string {
+	var words []string
+	for _, word := range strings.Split(name, "_") {
+		if word != "" {
+			words = append(words, strings.ToUpper(word[:1])+word[1:])
+		}
+	}
+	return strings.Join(words, "")
+}
@@ -0,0 +1,59 @@
+package dao
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+)
+
+// History tables keep every version of the resources of a kind, see api.ResourceVersion.
+// The DAOs record a version whenever they write a resource, with the session of the write
+// so that the version is committed, or rolled back, with it.
+
+// recordVersion closes the current version of the resource and records its new state.
+func recordVersion(g2 *gorm.DB, table string, resourceID string, orgID string, operation api.EventType, resource interface{}) error {
+	document, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := g2.Table(table).Where("resource_id = ? AND valid_to IS NULL", resourceID).Update("valid_to", now).Error; err != nil {
+		return err
+	}
+	return g2.Table(table).Create(&api.ResourceVersion{
+		ResourceID: resourceID,
+		OrgID:      orgID,
+		Operation:  operation,
+		ValidFrom:  now,
+		Document:   string(document),
+	}).Error
+}
+
+// findVersions returns the versions of the resource, oldest first.
+func findVersions(g2 *gorm.DB, table string, resourceID string) (api.ResourceVersionList, error) {
+	versions := api.ResourceVersionList{}
+	if err := g2.Table(table).Where("resource_id = ?", resourceID).Order("valid_from, id").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// findVersionAsOf returns the version of the resource valid at the given time.
+// it returns gorm.ErrRecordNotFound when the resource didn't exist at that time, or was deleted.
+func findVersionAsOf(g2 *gorm.DB, table string, resourceID string, asOf time.Time) (*api.ResourceVersion, error) {
+	var version api.ResourceVersion
+	err := g2.Table(table).
+		Where("resource_id = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", resourceID, asOf, asOf).
+		Order("valid_from DESC").
+		Take(&version).Error
+	if err != nil {
+		return nil, err
+	}
+	if version.Operation == api.DeleteEventType {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &version, nil
+}
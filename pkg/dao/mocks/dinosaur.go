@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/openshift-online/rh-trex/pkg/dao"
 
@@ -43,6 +44,26 @@ func (d *dinosaurDaoMock) Delete(ctx context.Context, id string) error {
 	return errors.NotImplemented("Dinosaur").AsError()
 }
 
+func (d *dinosaurDaoMock) Restore(ctx context.Context, id string) error {
+	return errors.NotImplemented("Dinosaur").AsError()
+}
+
+func (d *dinosaurDaoMock) Purge(ctx context.Context, id string) (bool, error) {
+	return false, errors.NotImplemented("Dinosaur").AsError()
+}
+
+func (d *dinosaurDaoMock) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	return 0, errors.NotImplemented("Dinosaur").AsError()
+}
+
+func (d *dinosaurDaoMock) History(ctx context.Context, id string) (api.ResourceVersionList, error) {
+	return nil, errors.NotImplemented("Dinosaur").AsError()
+}
+
+func (d *dinosaurDaoMock) GetAsOf(ctx context.Context, id string, asOf time.Time) (*api.Dinosaur, error) {
+	return nil, errors.NotImplemented("Dinosaur").AsError()
+}
+
 func (d *dinosaurDaoMock) FindByIDs(ctx context.Context, ids []string) (api.DinosaurList, error) {
 	return nil, errors.NotImplemented("Dinosaur").AsError()
 }
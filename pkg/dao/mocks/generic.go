@@ -3,6 +3,8 @@ package mocks
 import (
 	"context"
 
+	"gorm.io/gorm/schema"
+
 	"github.com/openshift-online/rh-trex/pkg/dao"
 )
 
@@ -10,11 +12,15 @@ var _ dao.GenericDao = &genericDaoMock{}
 
 type genericDaoMock struct {
 	preload  string
+	columns  []string
 	orderBy  string
 	joins    string
 	group    string
 	wheres   []dao.Where
 	model    interface{}
+	unscoped bool
+	// PlanCost is the cost of the query plans of the pages of the records, see ExplainCost, 0 when nil
+	PlanCost func(offset int, limit int) float64
 }
 
 func NewGenericDao() *genericDaoMock {
@@ -30,8 +36,9 @@ func (g *genericDaoMock) Fetch(offset int, limit int, resourceList interface{})
 
 func (g *genericDaoMock) GetInstanceDao(ctx context.Context, model interface{}) dao.GenericDao {
 	return &genericDaoMock{
-		model:  model,
-		wheres: []dao.Where{},
+		model:    model,
+		wheres:   []dao.Where{},
+		PlanCost: g.PlanCost,
 	}
 }
 
@@ -39,11 +46,15 @@ func (g *genericDaoMock) Preload(preload string) {
 	g.preload = preload
 }
 
+func (g *genericDaoMock) Select(columns []string) {
+	g.columns = columns
+}
+
 func (g *genericDaoMock) OrderBy(orderBy string) {
 	g.orderBy = orderBy
 }
 
-func (g *genericDaoMock) Joins(sql string) {
+func (g *genericDaoMock) Joins(sql string, values ...any) {
 	g.joins = sql
 }
 
@@ -55,11 +66,46 @@ func (g *genericDaoMock) Where(where dao.Where) {
 	g.wheres = append(g.wheres, where)
 }
 
+func (g *genericDaoMock) Unscoped() {
+	g.unscoped = true
+}
+
 func (g *genericDaoMock) Count(model interface{}, total *int64) {
 	// Mock implementation - sets count to 0
 	*total = 0
 }
 
+func (g *genericDaoMock) EstimateCount(model interface{}, total *int64) error {
+	// Mock implementation - sets the estimate to 0
+	*total = 0
+	return nil
+}
+
+func (g *genericDaoMock) ExplainCost(model interface{}, offset int, limit int, cost *float64) error {
+	// Mock implementation - sets the cost to the PlanCost of the page
+	*cost = 0
+	if g.PlanCost != nil {
+		*cost = g.PlanCost(offset, limit)
+	}
+	return nil
+}
+
+func (g *genericDaoMock) Aggregate(columns []string, aggregates []string, rows *[]map[string]interface{}) error {
+	// Mock implementation - loads no groups
+	*rows = []map[string]interface{}{}
+	return nil
+}
+
+func (g *genericDaoMock) Stream(newResource func() interface{}, each func(resource interface{}) error) error {
+	// Mock implementation - streams no resources
+	return nil
+}
+
+func (g *genericDaoMock) Take(resource interface{}) error {
+	// Mock implementation - returns no error
+	return nil
+}
+
 func (g *genericDaoMock) Validate(resourceList interface{}) error {
 	// Mock implementation - returns no error
 	return nil
@@ -74,3 +120,8 @@ func (g *genericDaoMock) GetTableRelation(fieldName string) (dao.TableRelation,
 	// Mock implementation - returns empty relation and false
 	return dao.TableRelation{}, false
 }
+
+func (g *genericDaoMock) GetColumnField(column string) (*schema.Field, bool) {
+	// Mock implementation - returns no field and false
+	return nil, false
+}
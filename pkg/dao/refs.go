@@ -0,0 +1,24 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/openshift-online/rh-trex/pkg/db"
+)
+
+// checkRef returns a db.ConstraintError when the field of the table refers to an id of the model that the session
+// can't reach, e.g. a record of another organization: the tenant scope confines the reads, not the ids written.
+// An empty id refers to nothing.
+func checkRef(g2 *gorm.DB, table string, field string, model interface{}, id string) error {
+	if id == "" {
+		return nil
+	}
+	var count int64
+	if err := g2.Session(&gorm.Session{}).Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return db.NewReferenceError(table, field, id)
+	}
+	return nil
+}
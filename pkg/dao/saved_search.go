@@ -0,0 +1,75 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/db"
+)
+
+// SavedSearchDao reads and writes the saved searches of an owner, the ones of the other owners are not found
+type SavedSearchDao interface {
+	Get(ctx context.Context, owner string, id string) (*api.SavedSearch, error)
+	Create(ctx context.Context, savedSearch *api.SavedSearch) (*api.SavedSearch, error)
+	Replace(ctx context.Context, savedSearch *api.SavedSearch) (*api.SavedSearch, error)
+	Delete(ctx context.Context, owner string, id string) error
+	FindByOwner(ctx context.Context, owner string) (api.SavedSearchList, error)
+}
+
+var _ SavedSearchDao = &sqlSavedSearchDao{}
+
+type sqlSavedSearchDao struct {
+	sessionFactory *db.SessionFactory
+}
+
+func NewSavedSearchDao(sessionFactory *db.SessionFactory) SavedSearchDao {
+	return &sqlSavedSearchDao{sessionFactory: sessionFactory}
+}
+
+func (d *sqlSavedSearchDao) Get(ctx context.Context, owner string, id string) (*api.SavedSearch, error) {
+	g2 := (*d.sessionFactory).New(ctx)
+	var savedSearch api.SavedSearch
+	if err := g2.Take(&savedSearch, "id = ? AND owner = ?", id, owner).Error; err != nil {
+		return nil, err
+	}
+	return &savedSearch, nil
+}
+
+func (d *sqlSavedSearchDao) Create(ctx context.Context, savedSearch *api.SavedSearch) (*api.SavedSearch, error) {
+	g2 := (*d.sessionFactory).New(ctx)
+	if err := g2.Omit(clause.Associations).Create(savedSearch).Error; err != nil {
+		db.MarkForRollback(ctx, err)
+		return nil, err
+	}
+	return savedSearch, nil
+}
+
+func (d *sqlSavedSearchDao) Replace(ctx context.Context, savedSearch *api.SavedSearch) (*api.SavedSearch, error) {
+	g2 := (*d.sessionFactory).New(ctx)
+	if err := g2.Omit(clause.Associations).Save(savedSearch).Error; err != nil {
+		db.MarkForRollback(ctx, err)
+		return nil, err
+	}
+	return savedSearch, nil
+}
+
+func (d *sqlSavedSearchDao) Delete(ctx context.Context, owner string, id string) error {
+	g2 := (*d.sessionFactory).New(ctx)
+	if err := g2.Omit(clause.Associations).Where("owner = ?", owner).Delete(&api.SavedSearch{Meta: api.Meta{ID: id}}).Error; err != nil {
+		db.MarkForRollback(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// FindByOwner returns the saved searches of the owner, ordered by kind and name
+func (d *sqlSavedSearchDao) FindByOwner(ctx context.Context, owner string) (api.SavedSearchList, error) {
+	g2 := (*d.sessionFactory).New(ctx)
+	savedSearches := api.SavedSearchList{}
+	if err := g2.Where("owner = ?", owner).Order("resource_kind, name").Find(&savedSearches).Error; err != nil {
+		return nil, err
+	}
+	return savedSearches, nil
+}
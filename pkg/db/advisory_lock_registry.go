@@ -0,0 +1,55 @@
+package db
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// HeldAdvisoryLock describes an advisory lock currently held by this process.
+type HeldAdvisoryLock struct {
+	Owner    string    `json:"owner"`
+	ID       string    `json:"id"`
+	Type     LockType  `json:"type"`
+	Acquired time.Time `json:"acquired"`
+}
+
+// heldLocks tracks the locks held by every AdvisoryLockFactory of this process,
+// service locators create a new factory for every service so the factories can't be asked directly.
+var heldLocks = &advisoryLockRegistry{locks: map[string]HeldAdvisoryLock{}}
+
+type advisoryLockRegistry struct {
+	mutex sync.RWMutex
+	locks map[string]HeldAdvisoryLock
+}
+
+func (r *advisoryLockRegistry) add(owner, id string, lockType LockType) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.locks[owner] = HeldAdvisoryLock{
+		Owner:    owner,
+		ID:       id,
+		Type:     lockType,
+		Acquired: time.Now(),
+	}
+}
+
+func (r *advisoryLockRegistry) remove(owner string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.locks, owner)
+}
+
+// HeldAdvisoryLocks returns the advisory locks held by this process, oldest first.
+func HeldAdvisoryLocks() []HeldAdvisoryLock {
+	heldLocks.mutex.RLock()
+	locks := make([]HeldAdvisoryLock, 0, len(heldLocks.locks))
+	for _, l := range heldLocks.locks {
+		locks = append(locks, l)
+	}
+	heldLocks.mutex.RUnlock()
+	sort.Slice(locks, func(i, j int) bool {
+		return locks[i].Acquired.Before(locks[j].Acquired)
+	})
+	return locks
+}
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/openshift-online/rh-trex/pkg/config"
+	dbContext "github.com/openshift-online/rh-trex/pkg/db/db_context"
 	"github.com/openshift-online/rh-trex/pkg/logger"
 	"gorm.io/gorm"
 )
@@ -22,8 +24,16 @@ const (
 	Migrations LockType = "migrations"
 	Dinosaurs  LockType = "dinosaurs"
 	Events     LockType = "events"
+	Purge      LockType = "purge"
 )
 
+// ErrAdvisoryLockTimeout is returned when a blocking advisory lock could not be obtained
+// within the configured lock timeout, or before the caller's context was done.
+var ErrAdvisoryLockTimeout = errors.New("timed out waiting for the advisory lock")
+
+// postgres SQLSTATE raised when lock_timeout expires
+const lockNotAvailable = "55P03"
+
 // LockFactory provides the blocking/unblocking locks based on PostgreSQL advisory lock.
 type LockFactory interface {
 	// NewAdvisoryLock constructs a new AdvisoryLock that is a blocking PostgreSQL advisory lock
@@ -37,17 +47,27 @@ type LockFactory interface {
 }
 
 type AdvisoryLockFactory struct {
-	connection SessionFactory
-	locks      advisoryLockMap
-	mutex      sync.RWMutex
+	connection  SessionFactory
+	locks       advisoryLockMap
+	mutex       sync.RWMutex
+	lockTimeout time.Duration
+	legacyKeys  bool
 }
 
 // NewAdvisoryLockFactory returns a new factory with AdvisoryLock stored in it.
-func NewAdvisoryLockFactory(connection SessionFactory) *AdvisoryLockFactory {
-	return &AdvisoryLockFactory{
+// Blocking locks wait at most config.AdvisoryLockTimeout, a nil config or a zero timeout waits
+// until the lock is obtained or the caller's context is done.
+// config.AdvisoryLockLegacyKeys selects the (int, int) lock keys used by previous releases.
+func NewAdvisoryLockFactory(connection SessionFactory, config *config.DatabaseConfig) *AdvisoryLockFactory {
+	f := &AdvisoryLockFactory{
 		connection: connection,
 		locks:      make(advisoryLockMap),
 	}
+	if config != nil {
+		f.lockTimeout = config.AdvisoryLockTimeout
+		f.legacyKeys = config.AdvisoryLockLegacyKeys
+	}
+	return f
 }
 
 func (f *AdvisoryLockFactory) NewAdvisoryLock(ctx context.Context, id string, lockType LockType) (string, error) {
@@ -59,19 +79,25 @@ func (f *AdvisoryLockFactory) NewAdvisoryLock(ctx context.Context, id string, lo
 	}
 
 	// obtain the advisory lock (blocking)
-	if err := lock.lock(); err != nil {
-		UpdateAdvisoryLockCountMetric(lockType, "lock error")
-		errMsg := fmt.Sprintf("error obtaining the advisory lock for id %s type %s, %v", id, lockType, err)
-		log.Error(errMsg)
-		// the lock transaction is already started, if error happens, we return the transaction id, so that the caller
-		// can end this transaction.
-		return *lock.uuid, fmt.Errorf("%s", errMsg)
+	if err := lock.lock(ctx, f.lockTimeout); err != nil {
+		status := "lock error"
+		if errors.Is(err, ErrAdvisoryLockTimeout) {
+			status = "timeout"
+		}
+		UpdateAdvisoryLockCountMetric(lockType, status)
+		UpdateAdvisoryLockDurationMetric(lockType, status, lock.startTime)
+		log.Error(fmt.Sprintf("error obtaining the advisory lock for id %s type %s, %v", id, lockType, err))
+		// the lock transaction is already started, end it here since the lock was never stored in the factory
+		// and the caller's Unlock will not find it.
+		lock.abort()
+		return *lock.uuid, fmt.Errorf("error obtaining the advisory lock for id %s type %s: %w", id, lockType, err)
 	}
 
 	log.V(4).Info(fmt.Sprintf("Locked advisory lock id=%s type=%s - owner=%s", id, lockType, *lock.uuid))
 	f.mutex.Lock()
 	f.locks[*lock.uuid] = lock
 	f.mutex.Unlock()
+	heldLocks.add(*lock.uuid, id, lockType)
 	return *lock.uuid, nil
 }
 
@@ -87,17 +113,20 @@ func (f *AdvisoryLockFactory) NewNonBlockingLock(ctx context.Context, id string,
 	acquired, err := lock.nonBlockingLock()
 	if err != nil {
 		UpdateAdvisoryLockCountMetric(lockType, "lock error")
-		errMsg := fmt.Sprintf("error obtaining the non blocking advisory lock for id %s type %s, %v", id, lockType, err)
-		log.Error(errMsg)
-		// the lock transaction is already started, if error happens, we return the transaction id, so that the caller
-		// can end this transaction.
-		return *lock.uuid, false, fmt.Errorf("%s", errMsg)
+		log.Error(fmt.Sprintf("error obtaining the non blocking advisory lock for id %s type %s, %v", id, lockType, err))
+		// the lock transaction is already started, end it here since the lock was never stored in the factory
+		// and the caller's Unlock will not find it.
+		lock.abort()
+		return *lock.uuid, false, fmt.Errorf("error obtaining the non blocking advisory lock for id %s type %s: %w", id, lockType, err)
 	}
 
 	log.V(4).Info(fmt.Sprintf("Locked non blocking advisory lock id=%s type=%s - owner=%s", id, lockType, *lock.uuid))
 	f.mutex.Lock()
 	f.locks[*lock.uuid] = lock
 	f.mutex.Unlock()
+	if acquired {
+		heldLocks.add(*lock.uuid, id, lockType)
+	}
 	return *lock.uuid, acquired, nil
 }
 
@@ -114,6 +143,7 @@ func (f *AdvisoryLockFactory) newLock(ctx context.Context, id string, lockType L
 	lock.uuid = &lockOwnerID
 	lock.id = &id
 	lock.lockType = &lockType
+	lock.legacyKeys = f.legacyKeys
 
 	return lock, nil
 }
@@ -137,49 +167,57 @@ func (f *AdvisoryLockFactory) Unlock(ctx context.Context, uuid string) {
 		return
 	}
 
-	lockType := *lock.lockType
-	lockID := "<missing>"
-	if lock.id != nil {
-		lockID = *lock.id
-	}
-
-	if err := lock.unlock(); err != nil {
-		UpdateAdvisoryLockCountMetric(lockType, "unlock error")
-		log.Extra("lockID", lockID).Extra("owner", uuid).Error(fmt.Sprintf("Could not unlock, %v", err))
-	}
-
-	UpdateAdvisoryLockCountMetric(lockType, "OK")
-	UpdateAdvisoryLockDurationMetric(lockType, "OK", lock.startTime)
-
-	log.V(4).Info(fmt.Sprintf("Unlocked lock id=%s type=%s - owner=%s", lockID, lockType, uuid))
-
-	f.mutex.Lock()
-	delete(f.locks, uuid)
-	f.mutex.Unlock()
+	// the lock is released once the request transaction is resolved, not before its writes are committed.
+	afterTransaction(ctx, func() {
+		lockType := *lock.lockType
+		lockID := "<missing>"
+		if lock.id != nil {
+			lockID = *lock.id
+		}
+
+		if err := lock.unlock(); err != nil {
+			UpdateAdvisoryLockCountMetric(lockType, "unlock error")
+			log.Extra("lockID", lockID).Extra("owner", uuid).Error(fmt.Sprintf("Could not unlock, %v", err))
+		}
+
+		UpdateAdvisoryLockCountMetric(lockType, "OK")
+		UpdateAdvisoryLockDurationMetric(lockType, "OK", lock.startTime)
+
+		log.V(4).Info(fmt.Sprintf("Unlocked lock id=%s type=%s - owner=%s", lockID, lockType, uuid))
+
+		f.mutex.Lock()
+		delete(f.locks, uuid)
+		f.mutex.Unlock()
+		heldLocks.remove(uuid)
+	})
 }
 
 // AdvisoryLock represents a postgres advisory lock
 //
 //	begin                                       # start a Tx
-//	select pg_advisory_xact_lock(id, lockType)  # obtain the lock (blocking)
+//	select pg_advisory_xact_lock(key)           # obtain the lock (blocking)
 //	end                                         # end the Tx and release the lock
 //
+// key is a 64-bit hash of (lockType, id). With legacy keys the lock is obtained with
+// pg_advisory_xact_lock(hash(id), hash(lockType)) using two 32-bit hashes instead.
+//
 // UUID is a way to own the lock. Only the very first
 // service call that owns the lock will have the correct UUID. This is necessary
 // to allow functions to call other service functions as part of the same lock (id, lockType).
 type AdvisoryLock struct {
-	g2        *gorm.DB
-	txid      int64
-	uuid      *string
-	id        *string
-	lockType  *LockType
-	startTime time.Time
+	g2         *gorm.DB
+	txid       int64
+	uuid       *string
+	id         *string
+	lockType   *LockType
+	startTime  time.Time
+	legacyKeys bool
 }
 
 // newAdvisoryLock constructs a new AdvisoryLock object.
 func newAdvisoryLock(ctx context.Context, connection SessionFactory) (*AdvisoryLock, error) {
-	// it requires a new DB session to start the advisory lock.
-	g2 := connection.New(ctx)
+	// it requires a new DB session to start the advisory lock, not bound to the request transaction.
+	g2 := connection.New(dbContext.WithoutTransaction(ctx))
 
 	// start a Tx to ensure gorm will obtain/release the lock using a same connection.
 	tx := g2.Begin()
@@ -201,8 +239,9 @@ func newAdvisoryLock(ctx context.Context, connection SessionFactory) (*AdvisoryL
 
 // lock calls select pg_advisory_xact_lock(id, lockType) to obtain the lock defined by (id, lockType).
 // it is blocked if some other thread currently is holding the same lock (id, lockType).
-// if blocked, it can be unblocked or timed out when overloaded.
-func (l *AdvisoryLock) lock() error {
+// if blocked, it waits at most timeout (when > 0) and returns ErrAdvisoryLockTimeout when the
+// wait expires or ctx is done before the lock is obtained.
+func (l *AdvisoryLock) lock(ctx context.Context, timeout time.Duration) error {
 	if l.g2 == nil {
 		return errors.New("AdvisoryLock: transaction is missing")
 	}
@@ -213,10 +252,23 @@ func (l *AdvisoryLock) lock() error {
 		return errors.New("AdvisoryLock: lockType is missing")
 	}
 
-	idAsInt := hash(*l.id)
-	typeAsInt := hash(string(*l.lockType))
-	err := l.g2.Exec("select pg_advisory_xact_lock(?, ?)", idAsInt, typeAsInt).Error
+	if timeout > 0 {
+		// lock_timeout also bounds advisory lock waits. SET LOCAL scopes it to the lock transaction.
+		err := l.g2.Exec(fmt.Sprintf("SET LOCAL lock_timeout = %d", timeout.Milliseconds())).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	keys, args := l.keys()
+	err := l.g2.Exec(fmt.Sprintf("select pg_advisory_xact_lock(%s)", keys), args...).Error
 	if err != nil {
+		if isLockTimeout(err) {
+			return fmt.Errorf("%w after %s", ErrAdvisoryLockTimeout, timeout)
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: %w", ErrAdvisoryLockTimeout, ctx.Err())
+		}
 		return err
 	}
 	return nil
@@ -233,11 +285,10 @@ func (l *AdvisoryLock) nonBlockingLock() (bool, error) {
 		return false, errors.New("AdvisoryLock: lockType is missing")
 	}
 
-	idAsInt := hash(*l.id)
-	typeAsInt := hash(string(*l.lockType))
+	keys, args := l.keys()
 	var acquired bool
 	var result string
-	err := l.g2.Raw("select pg_try_advisory_xact_lock(?, ?)", idAsInt, typeAsInt).Scan(&result).Error
+	err := l.g2.Raw(fmt.Sprintf("select pg_try_advisory_xact_lock(%s)", keys), args...).Scan(&result).Error
 	if err != nil {
 		return false, err
 	}
@@ -248,6 +299,30 @@ func (l *AdvisoryLock) nonBlockingLock() (bool, error) {
 	return acquired, nil
 }
 
+// keys returns the placeholders and arguments identifying the lock in the pg_advisory_* functions.
+func (l *AdvisoryLock) keys() (string, []interface{}) {
+	return advisoryLockKeys(*l.id, *l.lockType, l.legacyKeys)
+}
+
+// advisoryLockKeys returns the arguments identifying the lock (id, lockType) in the pg_advisory_* functions
+// along with their gorm placeholders.
+func advisoryLockKeys(id string, lockType LockType, legacyKeys bool) (string, []interface{}) {
+	if legacyKeys {
+		return "?, ?", []interface{}{hash(id), hash(string(lockType))}
+	}
+	return "?", []interface{}{hash64(lockType, id)}
+}
+
+// abort rolls back the lock transaction of a lock that was not obtained.
+func (l *AdvisoryLock) abort() {
+	if l.g2 == nil {
+		return
+	}
+	// the transaction may already be ended by a cancelled context, nothing to do in that case.
+	_ = l.g2.Rollback().Error
+	l.g2 = nil
+}
+
 func (l *AdvisoryLock) unlock() error {
 	if l.g2 == nil {
 		return errors.New("AdvisoryLock: transaction is missing")
@@ -262,6 +337,22 @@ func (l *AdvisoryLock) unlock() error {
 	return err
 }
 
+// isLockTimeout reports whether err is postgres' lock_not_available error raised by lock_timeout.
+func isLockTimeout(err error) bool {
+	return SQLState(err) == lockNotAvailable
+}
+
+// hash64 combines lockType and id into a single 64-bit key (postgres bigint).
+// the 0 byte separates both parts so that ("ab", "c") and ("a", "bc") do not share a key.
+func hash64(lockType LockType, id string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(lockType))
+	h.Write([]byte{0})
+	h.Write([]byte(id))
+	// Sum64() returns uint64. needs conversion.
+	return int64(h.Sum64())
+}
+
 // hash string to int32 (postgres integer)
 // https://pkg.go.dev/math#pkg-constants
 // https://www.postgresql.org/docs/12/datatype-numeric.html
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestAdvisoryLockHash64(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(hash64(Dinosaurs, "abc")).To(Equal(hash64(Dinosaurs, "abc")))
+	Expect(hash64(Dinosaurs, "abc")).NotTo(Equal(hash64(Events, "abc")))
+	Expect(hash64("ab", "c")).NotTo(Equal(hash64("a", "bc")))
+}
+
+func TestHeldAdvisoryLocks(t *testing.T) {
+	RegisterTestingT(t)
+
+	owner := "owner"
+	heldLocks.add(owner, "abc", Dinosaurs)
+	Expect(HeldAdvisoryLocks()).To(ContainElement(HaveField("Owner", owner)))
+
+	heldLocks.remove(owner)
+	Expect(HeldAdvisoryLocks()).NotTo(ContainElement(HaveField("Owner", owner)))
+}
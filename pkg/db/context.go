@@ -2,12 +2,15 @@ package db
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	dbContext "github.com/openshift-online/rh-trex/pkg/db/db_context"
 	"github.com/openshift-online/rh-trex/pkg/logger"
 )
 
 // NewContext returns a new context with transaction stored in it.
+// Session advisory locks obtained with the returned context are re-entrant, see WithAdvisoryLockScope.
 // Upon error, the original context is still returned along with an error
 func NewContext(ctx context.Context, connection SessionFactory) (context.Context, error) {
 	tx, err := newTransaction(ctx, connection)
@@ -16,32 +19,73 @@ func NewContext(ctx context.Context, connection SessionFactory) (context.Context
 	}
 
 	ctx = dbContext.WithTransaction(ctx, tx)
+	ctx = WithAdvisoryLockScope(ctx)
 
 	return ctx, nil
 }
 
 // Resolve resolves the current transaction according to the rollback flag.
 func Resolve(ctx context.Context) {
+	_ = resolve(ctx)
+}
+
+// resolve commits or rolls back the current transaction and returns the commit or rollback error.
+func resolve(ctx context.Context) error {
 	log := logger.NewOCMLogger(ctx)
 	tx, ok := dbContext.Transaction(ctx)
 	if !ok {
 		log.Error("Could not retrieve transaction from context")
-		return
+		return errors.New("could not retrieve transaction from context")
+	}
+	if tx.Tx() == nil {
+		// already resolved, e.g. committed by RetryTransaction
+		return nil
 	}
 
 	if tx.MarkedForRollback() {
 		if err := tx.Rollback(); err != nil {
 			log.Extra("error", err.Error()).Error("Could not rollback transaction")
-			return
+			return err
 		}
 		log.Infof("Rolled back transaction")
 	} else {
 		if err := tx.Commit(); err != nil {
 			// TODO:  what does the user see when this occurs? seems like they will get a false positive
 			log.Extra("error", err.Error()).Error("Could not commit transaction")
-			return
+			return err
 		}
 	}
+	return nil
+}
+
+// WithTransaction runs fn with a new transaction stored in the context, for code paths that are not
+// HTTP requests (controllers, commands, jobs) and don't get one from TransactionMiddleware.
+// The transaction is rolled back when fn returns an error, panics or calls MarkForRollback,
+// and committed otherwise. fn's error, or the commit error, is returned.
+func WithTransaction(ctx context.Context, connection SessionFactory, fn func(ctx context.Context) error) (err error) {
+	txCtx, err := NewContext(ctx, connection)
+	if err != nil {
+		return err
+	}
+	if tx, _ := dbContext.Transaction(txCtx); tx == nil {
+		// no database, e.g. unit tests with mocked DAOs
+		return fn(txCtx)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			MarkForRollback(txCtx, fmt.Errorf("panic: %v", p))
+			_ = resolve(txCtx)
+			panic(p)
+		}
+	}()
+
+	if err = fn(txCtx); err != nil {
+		MarkForRollback(txCtx, err)
+		_ = resolve(txCtx)
+		return err
+	}
+	return resolve(txCtx)
 }
 
 // MarkForRollback flags the transaction stored in the context for rollback and logs whatever error caused the rollback
@@ -53,5 +97,46 @@ func MarkForRollback(ctx context.Context, err error) {
 		return
 	}
 	transaction.SetRollbackFlag(true)
+	if transaction.RollbackError() == nil {
+		transaction.SetRollbackError(err)
+	}
 	log.Infof("Marked transaction for rollback, err: %v", err)
 }
+
+// WithSavepoint runs fn within a savepoint of the transaction stored in the context.
+// When fn returns an error the work done by fn is rolled back, including any MarkForRollback it called,
+// and the error is returned so the caller can recover while keeping the rest of the transaction.
+// Without a transaction in the context fn is run as is.
+func WithSavepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, ok := dbContext.ActiveTransaction(ctx)
+	if !ok {
+		return fn(ctx)
+	}
+
+	name, err := tx.Savepoint()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(ctx); err != nil {
+		if rbErr := tx.RollbackTo(name); rbErr != nil {
+			return fmt.Errorf("%w (rolling back to savepoint: %v)", err, rbErr)
+		}
+		if relErr := tx.Release(name); relErr != nil {
+			return fmt.Errorf("%w (releasing savepoint: %v)", err, relErr)
+		}
+		return err
+	}
+	return tx.Release(name)
+}
+
+// afterTransaction runs fn once the transaction stored in the context is resolved, or right away
+// when there is none. Advisory locks are released this way so they keep protecting the writes
+// of the request until those writes are committed.
+func afterTransaction(ctx context.Context, fn func()) {
+	if tx, ok := dbContext.ActiveTransaction(ctx); ok {
+		tx.AfterResolve(fn)
+		return
+	}
+	fn()
+}
@@ -19,6 +19,21 @@ func WithTransaction(ctx context.Context, tx *transaction.Transaction) context.C
 	return context.WithValue(ctx, transactionKey, tx)
 }
 
+// WithoutTransaction returns a new context that hides the transaction of ctx,
+// sessions created with it are not bound to the transaction.
+func WithoutTransaction(ctx context.Context) context.Context {
+	return context.WithValue(ctx, transactionKey, (*transaction.Transaction)(nil))
+}
+
+// ActiveTransaction returns the transaction of the context when it is not ended yet.
+func ActiveTransaction(ctx context.Context) (*transaction.Transaction, bool) {
+	tx, ok := Transaction(ctx)
+	if !ok || tx == nil || tx.Tx() == nil {
+		return nil, false
+	}
+	return tx, true
+}
+
 // Transaction extracts the transaction value from the context
 func Transaction(ctx context.Context) (tx *transaction.Transaction, ok bool) {
 	tx, ok = ctx.Value(transactionKey).(*transaction.Transaction)
@@ -1,9 +1,36 @@
 package db_session
 
-import "sync"
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"github.com/openshift-online/rh-trex/pkg/config"
+	"github.com/openshift-online/rh-trex/pkg/db/db_context"
+)
 
 const (
 	disable = "disable"
 )
 
 var once sync.Once
+
+// configurePool applies the connection pool limits from the database config to dbx.
+func configurePool(dbx *sql.DB, config *config.DatabaseConfig) {
+	dbx.SetMaxOpenConns(config.MaxOpenConnections)
+	dbx.SetMaxIdleConns(config.MaxIdleConnections)
+	dbx.SetConnMaxLifetime(config.ConnMaxLifetime)
+	dbx.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+}
+
+// bindTransaction makes the session run its statements in the transaction stored in the context, if any,
+// so that every DAO call of a request commits or rolls back together with the request transaction.
+// gorm does not begin its default transaction on top of it, nested gorm transactions use savepoints.
+func bindTransaction(ctx context.Context, conn *gorm.DB) *gorm.DB {
+	if tx, ok := db_context.ActiveTransaction(ctx); ok {
+		conn.Statement.ConnPool = tx.Tx()
+	}
+	return conn
+}
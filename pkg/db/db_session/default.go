@@ -49,9 +49,9 @@ func (f *Default) Init(config *config.DatabaseConfig) {
 		)
 
 		// Open connection to DB via standard library
-		dbx, err = sql.Open(config.Dialect, config.ConnectionString(config.SSLMode != disable))
+		dbx, err = sql.Open(config.Dialect, config.PoolConnectionString(config.SSLMode != disable))
 		if err != nil {
-			dbx, err = sql.Open(config.Dialect, config.ConnectionString(false))
+			dbx, err = sql.Open(config.Dialect, config.PoolConnectionString(false))
 			if err != nil {
 				panic(fmt.Sprintf(
 					"SQL failed to connect to %s database %s with connection string: %s\nError: %s",
@@ -62,7 +62,7 @@ func (f *Default) Init(config *config.DatabaseConfig) {
 				))
 			}
 		}
-		dbx.SetMaxOpenConns(config.MaxOpenConnections)
+		configurePool(dbx, config)
 
 		// Connect GORM to use the same connection
 		conf := &gorm.Config{
@@ -86,6 +86,9 @@ func (f *Default) Init(config *config.DatabaseConfig) {
 				err.Error(),
 			))
 		}
+		if err := db.RegisterTenantScope(g2); err != nil {
+			panic(fmt.Sprintf("GORM failed to register the tenant scope: %s", err.Error()))
+		}
 
 		f.config = config
 		f.g2 = g2
@@ -147,7 +150,7 @@ func (f *Default) New(ctx context.Context) *gorm.DB {
 	if f.config.Debug {
 		conn = conn.Debug()
 	}
-	return conn
+	return bindTransaction(ctx, conn)
 }
 
 func (f *Default) CheckConnection() error {
@@ -67,7 +67,7 @@ func (f *Test) Init(config *config.DatabaseConfig) {
 
 func initDatabase(config *config.DatabaseConfig, migrate func(db2 *gorm.DB) error) error {
 	// - Connect to `template1` DB
-	dbx, g2, cleanup := connect("template1", config)
+	dbx, g2, cleanup := connect("template1", config, false)
 	defer cleanup()
 
 	for _, err := dbx.Exec(`select 1`); err != nil; {
@@ -80,7 +80,7 @@ func initDatabase(config *config.DatabaseConfig, migrate func(db2 *gorm.DB) erro
 
 func resetDB(config *config.DatabaseConfig) error {
 	// Reconnect to the default `postgres` database, so we can drop the existing db and recreate it
-	dbx, _, cleanup := connect("postgres", config)
+	dbx, _, cleanup := connect("postgres", config, false)
 	defer cleanup()
 
 	// Drop `all` connections to both `template1` and AMS DB, so it can be dropped and created
@@ -104,17 +104,22 @@ func resetDB(config *config.DatabaseConfig) error {
 	return nil
 }
 
-// connect to database specified by `name` and return connections + cleanup function
-func connect(name string, config *config.DatabaseConfig) (*sql.DB, *gorm.DB, func()) {
+// connect to database specified by `name` and return connections + cleanup function,
+// the pool serving the API sets the session parameters of the config
+func connect(name string, config *config.DatabaseConfig, pool bool) (*sql.DB, *gorm.DB, func()) {
 	var (
 		dbx *sql.DB
 		g2  *gorm.DB
 		err error
 	)
 
-	dbx, err = sql.Open(config.Dialect, config.ConnectionStringWithName(name, config.SSLMode != disable))
+	connectionString := config.ConnectionStringWithName
+	if pool {
+		connectionString = config.PoolConnectionStringWithName
+	}
+	dbx, err = sql.Open(config.Dialect, connectionString(name, config.SSLMode != disable))
 	if err != nil {
-		dbx, err = sql.Open(config.Dialect, config.ConnectionStringWithName(name, false))
+		dbx, err = sql.Open(config.Dialect, connectionString(name, false))
 		if err != nil {
 			panic(fmt.Sprintf(
 				"SQL failed to connect to %s database %s with connection string: %s\nError: %s",
@@ -150,6 +155,9 @@ func connect(name string, config *config.DatabaseConfig) (*sql.DB, *gorm.DB, fun
 			err.Error(),
 		))
 	}
+	if err := db.RegisterTenantScope(g2); err != nil {
+		panic(fmt.Sprintf("GORM failed to register the tenant scope: %s", err.Error()))
+	}
 
 	return dbx, g2, func() {
 		if err := dbx.Close(); err != nil {
@@ -176,8 +184,8 @@ func connectFactory(config *config.DatabaseConfig) (*sql.DB, *gorm.DB) {
 		dbx *sql.DB
 		g2  *gorm.DB
 	)
-	dbx, g2, _ = connect(config.Name, config)
-	dbx.SetMaxOpenConns(config.MaxOpenConnections)
+	dbx, g2, _ = connect(config.Name, config, true)
+	configurePool(dbx, config)
 
 	return dbx, g2
 }
@@ -200,7 +208,7 @@ func (f *Test) New(ctx context.Context) *gorm.DB {
 	if f.config.Debug {
 		conn = conn.Debug()
 	}
-	return conn
+	return bindTransaction(ctx, conn)
 }
 
 // CheckConnection checks to ensure a connection is present
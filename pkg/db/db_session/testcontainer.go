@@ -61,7 +61,7 @@ func (f *Testcontainer) Init(config *config.DatabaseConfig) {
 	f.container = container
 
 	// Get connection string from container
-	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	connStr, err := container.ConnectionString(ctx, append([]string{"sslmode=disable"}, config.SessionParameters()...)...)
 	if err != nil {
 		glog.Fatalf("Failed to get connection string from testcontainer: %s", err)
 	}
@@ -75,7 +75,7 @@ func (f *Testcontainer) Init(config *config.DatabaseConfig) {
 	}
 
 	// Configure connection pool
-	f.sqlDB.SetMaxOpenConns(config.MaxOpenConnections)
+	configurePool(f.sqlDB, config)
 
 	// Connect GORM to use the same connection
 	conf := &gorm.Config{
@@ -96,6 +96,9 @@ func (f *Testcontainer) Init(config *config.DatabaseConfig) {
 	if err != nil {
 		glog.Fatalf("Failed to connect GORM to testcontainer database: %s", err)
 	}
+	if err := db.RegisterTenantScope(f.g2); err != nil {
+		glog.Fatalf("Failed to register the tenant scope: %s", err)
+	}
 
 	// Run migrations
 	glog.Infof("Running database migrations on testcontainer...")
@@ -118,7 +121,7 @@ func (f *Testcontainer) New(ctx context.Context) *gorm.DB {
 	if f.config.Debug {
 		conn = conn.Debug()
 	}
-	return conn
+	return bindTransaction(ctx, conn)
 }
 
 func (f *Testcontainer) CheckConnection() error {
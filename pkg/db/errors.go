@@ -0,0 +1,141 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// postgres SQLSTATE codes of transient errors, the failed transaction may succeed when retried.
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// postgres SQLSTATE codes of integrity constraint violations
+const (
+	notNullViolation    = "23502"
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	checkViolation      = "23514"
+	exclusionViolation  = "23P01"
+)
+
+// ConstraintKind is the kind of integrity constraint a ConstraintError violates.
+type ConstraintKind string
+
+const (
+	UniqueConstraint     ConstraintKind = "unique"
+	ForeignKeyConstraint ConstraintKind = "foreign key"
+	NotNullConstraint    ConstraintKind = "not null"
+	CheckConstraint      ConstraintKind = "check"
+	ExclusionConstraint  ConstraintKind = "exclusion"
+)
+
+var constraintKinds = map[string]ConstraintKind{
+	notNullViolation:    NotNullConstraint,
+	foreignKeyViolation: ForeignKeyConstraint,
+	uniqueViolation:     UniqueConstraint,
+	checkViolation:      CheckConstraint,
+	exclusionViolation:  ExclusionConstraint,
+}
+
+// ConstraintError is an integrity constraint violation reported by postgres.
+type ConstraintError struct {
+	Kind       ConstraintKind
+	Table      string
+	Constraint string
+	// Fields are the columns involved in the violation, when postgres reports them.
+	Fields []string
+	// Referenced is set for foreign key violations raised when the row is still referenced by another table.
+	Referenced bool
+	err        error
+}
+
+func (e *ConstraintError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.err
+}
+
+// NewReferenceError returns the foreign key violation of the field of the table referencing a record
+// that doesn't exist, for the references checked before postgres does, or that it can't check.
+func NewReferenceError(table string, field string, id string) *ConstraintError {
+	return &ConstraintError{
+		Kind:   ForeignKeyConstraint,
+		Table:  table,
+		Fields: []string{field},
+		err:    fmt.Errorf("%s.%s references %s which does not exist", table, field, id),
+	}
+}
+
+// detailKeys matches the columns in the detail of unique, exclusion and foreign key violations,
+// e.g. Key (species)=(rex) already exists. or Key (a, b)=(1, 2) conflicts with existing key (a, b)=(1, 3).
+var detailKeys = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// TranslateError returns a *ConstraintError for integrity constraint violations and err as is otherwise.
+func TranslateError(err error) error {
+	kind, ok := constraintKinds[SQLState(err)]
+	if !ok {
+		return err
+	}
+	constraintErr := &ConstraintError{Kind: kind, err: err}
+
+	// lib/pq reports the details of the violation, other drivers only the SQLSTATE code.
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return constraintErr
+	}
+	constraintErr.Table = pqErr.Table
+	constraintErr.Constraint = pqErr.Constraint
+	if pqErr.Column != "" {
+		constraintErr.Fields = []string{pqErr.Column}
+	} else if m := detailKeys.FindStringSubmatch(pqErr.Detail); m != nil {
+		for _, field := range strings.Split(m[1], ",") {
+			constraintErr.Fields = append(constraintErr.Fields, strings.Trim(strings.TrimSpace(field), `"`))
+		}
+	}
+	constraintErr.Referenced = kind == ForeignKeyConstraint && strings.Contains(pqErr.Detail, "is still referenced")
+	return constraintErr
+}
+
+// FieldNames returns the fields of the violation for messages, or the constraint name when postgres did not report them.
+func (e *ConstraintError) FieldNames() string {
+	if len(e.Fields) > 0 {
+		return strings.Join(e.Fields, ", ")
+	}
+	if e.Constraint != "" {
+		return e.Constraint
+	}
+	return fmt.Sprintf("%s constraint", e.Kind)
+}
+
+// sqlStateError is implemented by the errors of both lib/pq and jackc/pgx.
+type sqlStateError interface {
+	SQLState() string
+}
+
+// SQLState returns the postgres SQLSTATE code of err, or "" when err is not a postgres error.
+func SQLState(err error) string {
+	var sqlErr sqlStateError
+	if errors.As(err, &sqlErr) {
+		return sqlErr.SQLState()
+	}
+	return ""
+}
+
+// IsRetryable reports whether err is a transient postgres error (serialization failure or deadlock)
+// after which retrying the transaction may succeed.
+func IsRetryable(err error) bool {
+	switch SQLState(err) {
+	case serializationFailure, deadlockDetected:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	. "github.com/onsi/gomega"
+)
+
+func TestIsRetryable(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(IsRetryable(&pq.Error{Code: serializationFailure})).To(BeTrue())
+	Expect(IsRetryable(fmt.Errorf("update: %w", &pq.Error{Code: deadlockDetected}))).To(BeTrue())
+	Expect(IsRetryable(&pq.Error{Code: "23505"})).To(BeFalse())
+	Expect(IsRetryable(errors.New("40001"))).To(BeFalse())
+	Expect(IsRetryable(nil)).To(BeFalse())
+}
+
+func TestTranslateError(t *testing.T) {
+	RegisterTestingT(t)
+
+	var constraintErr *ConstraintError
+
+	unique := &pq.Error{Code: uniqueViolation, Table: "dinosaurs", Constraint: "idx_species", Detail: "Key (species, name)=(rex, t) already exists."}
+	Expect(errors.As(TranslateError(fmt.Errorf("create: %w", unique)), &constraintErr)).To(BeTrue())
+	Expect(constraintErr.Kind).To(Equal(UniqueConstraint))
+	Expect(constraintErr.Fields).To(Equal([]string{"species", "name"}))
+	Expect(errors.Is(constraintErr, unique)).To(BeTrue())
+
+	notNull := &pq.Error{Code: notNullViolation, Column: "species"}
+	Expect(errors.As(TranslateError(notNull), &constraintErr)).To(BeTrue())
+	Expect(constraintErr.Kind).To(Equal(NotNullConstraint))
+	Expect(constraintErr.FieldNames()).To(Equal("species"))
+
+	referenced := &pq.Error{Code: foreignKeyViolation, Table: "nests", Detail: `Key (id)=(1) is still referenced from table "nests".`}
+	Expect(errors.As(TranslateError(referenced), &constraintErr)).To(BeTrue())
+	Expect(constraintErr.Referenced).To(BeTrue())
+
+	check := &pq.Error{Code: checkViolation, Constraint: "species_not_empty", Detail: "Failing row contains (1, )."}
+	Expect(errors.As(TranslateError(check), &constraintErr)).To(BeTrue())
+	Expect(constraintErr.FieldNames()).To(Equal("species_not_empty"))
+
+	other := errors.New("boom")
+	Expect(TranslateError(other)).To(Equal(other))
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func addAuditLogs() *gormigrate.Migration {
+	type AuditLog struct {
+		Model
+		Actor        string `gorm:"index"`
+		OperationID  string `gorm:"index"`
+		Method       string
+		Path         string
+		ResourceKind string  `gorm:"index:idx_audit_logs_resource"`
+		ResourceID   string  `gorm:"index:idx_audit_logs_resource"`
+		Before       *string `gorm:"type:jsonb"`
+		After        *string `gorm:"type:jsonb"`
+		Changes      *string `gorm:"type:jsonb"`
+		StatusCode   int
+		Outcome      string
+		Reason       string
+	}
+
+	return &gormigrate.Migration{
+		ID: "202610190900",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&AuditLog{}); err != nil {
+				return err
+			}
+			// the retention purge deletes by creation time
+			return tx.Exec("CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at)").Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&AuditLog{})
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func addDinosaurVersions() *gormigrate.Migration {
+	type DinosaurVersion struct {
+		ID         string     `gorm:"primary_key"`
+		ResourceID string     `gorm:"index:idx_dinosaur_versions_resource_valid_from"`
+		Operation  string     // Create|Update|Delete
+		ValidFrom  time.Time  `gorm:"index:idx_dinosaur_versions_resource_valid_from"`
+		ValidTo    *time.Time `gorm:"null"`
+		Document   string     `gorm:"type:jsonb"`
+	}
+
+	// the dinosaurs created before the history table get their current state as first version,
+	// and a delete version for the deleted ones.
+	backfill := `
+INSERT INTO dinosaur_versions (id, resource_id, operation, valid_from, valid_to, document)
+SELECT 'backfill-' || id, id, 'Update', updated_at, deleted_at,
+	json_build_object('ID', id, 'CreatedAt', created_at, 'UpdatedAt', updated_at, 'DeletedAt', NULL, 'Species', species)
+FROM dinosaurs;
+INSERT INTO dinosaur_versions (id, resource_id, operation, valid_from, document)
+SELECT 'backfill-delete-' || id, id, 'Delete', deleted_at,
+	json_build_object('ID', id, 'CreatedAt', created_at, 'UpdatedAt', updated_at, 'DeletedAt', deleted_at, 'Species', species)
+FROM dinosaurs WHERE deleted_at IS NOT NULL;`
+
+	return &gormigrate.Migration{
+		ID: "202610191400",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&DinosaurVersion{}); err != nil {
+				return err
+			}
+			return tx.Exec(backfill).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&DinosaurVersion{})
+		},
+	}
+}
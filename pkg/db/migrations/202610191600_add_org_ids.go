@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+// addOrgIDs adds the organization owning the records, the records created before belong to none
+// and stay reachable by the callers without organization, see the --require-org-id flag.
+func addOrgIDs() *gormigrate.Migration {
+	type Dinosaur struct {
+		OrgID string `gorm:"index;default:''"`
+	}
+	type Event struct {
+		OrgID string `gorm:"index;default:''"`
+	}
+	type AuditLog struct {
+		OrgID string `gorm:"index;default:''"`
+	}
+	type DinosaurVersion struct {
+		OrgID string `gorm:"index;default:''"`
+	}
+	models := []interface{}{&Dinosaur{}, &Event{}, &AuditLog{}, &DinosaurVersion{}}
+
+	return &gormigrate.Migration{
+		ID: "202610191600",
+		Migrate: func(tx *gorm.DB) error {
+			for _, model := range models {
+				if err := tx.Migrator().AddColumn(model, "OrgID"); err != nil {
+					return err
+				}
+				if err := tx.Migrator().CreateIndex(model, "OrgID"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, model := range models {
+				if err := tx.Migrator().DropColumn(model, "OrgID"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+// addLabels adds the labels of the records, the dinosaurs are searched by their labels
+// with containment (@>) predicates, which the GIN index serves.
+func addLabels() *gormigrate.Migration {
+	type Dinosaur struct {
+		Labels string `gorm:"type:jsonb;not null;default:'{}'"`
+	}
+	type Event struct {
+		Labels string `gorm:"type:jsonb;not null;default:'{}'"`
+	}
+	type AuditLog struct {
+		Labels string `gorm:"type:jsonb;not null;default:'{}'"`
+	}
+	models := []interface{}{&Dinosaur{}, &Event{}, &AuditLog{}}
+	indexed := []string{"dinosaurs"}
+
+	return &gormigrate.Migration{
+		ID: "202610191700",
+		Migrate: func(tx *gorm.DB) error {
+			for _, model := range models {
+				if err := tx.Migrator().AddColumn(model, "Labels"); err != nil {
+					return err
+				}
+			}
+			for _, table := range indexed {
+				if err := tx.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_labels ON %s USING GIN (labels)", table, table)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, model := range models {
+				if err := tx.Migrator().DropColumn(model, "Labels"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+// addDinosaurTextSearch adds the search_vector column of the dinosaurs, the full-text searches (q) match the
+// species in it
+func addDinosaurTextSearch() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610191800",
+		Migrate: func(tx *gorm.DB) error {
+			return AddTextSearchColumn(tx, "dinosaurs", "species")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE dinosaurs DROP COLUMN IF EXISTS search_vector").Error
+		},
+	}
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+func addSavedSearches() *gormigrate.Migration {
+	type SavedSearch struct {
+		Model
+		OrgID        string `gorm:"index;default:''"`
+		Labels       string `gorm:"type:jsonb;not null;default:'{}'"`
+		Owner        string
+		Name         string
+		ResourceKind string
+		Search       string
+		OrderBy      string
+		Fields       string
+	}
+
+	return &gormigrate.Migration{
+		ID: "202610191900",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&SavedSearch{}); err != nil {
+				return err
+			}
+			// the names are unique among the live saved searches of an owner and a kind, the views look them up by it
+			return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_saved_searches_owner_name ON saved_searches (owner, resource_kind, name) WHERE deleted_at IS NULL").Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&SavedSearch{})
+		},
+	}
+}
@@ -2,6 +2,7 @@ package migrations
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -28,6 +29,12 @@ import (
 var MigrationList = []*gormigrate.Migration{
 	addDinosaurs(),
 	addEvents(),
+	addAuditLogs(),
+	addDinosaurVersions(),
+	addOrgIDs(),
+	addLabels(),
+	addDinosaurTextSearch(),
+	addSavedSearches(),
 }
 
 // Model represents the base model struct. All entities will have this struct embedded.
@@ -59,3 +66,23 @@ func CreateFK(g2 *gorm.DB, fks ...fkMigration) error {
 	}
 	return nil
 }
+
+// TextSearchConfig is the text search configuration the search_vector columns are generated with, the full-text
+// queries use it as db.TextSearchConfig. It is declared here as the db package imports the migrations.
+const TextSearchConfig = "english"
+
+// AddTextSearchColumn adds the search_vector column of the table, the tsvector of the text of the columns generated
+// with TextSearchConfig, and the GIN index serving the full-text searches
+func AddTextSearchColumn(g2 *gorm.DB, table string, columns ...string) error {
+	texts := make([]string, 0, len(columns))
+	for _, column := range columns {
+		texts = append(texts, fmt.Sprintf("coalesce(%s, '')", column))
+	}
+	column := fmt.Sprintf(
+		"ALTER TABLE %s ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (to_tsvector('%s', %s)) STORED",
+		table, TextSearchConfig, strings.Join(texts, " || ' ' || "))
+	if err := g2.Exec(column).Error; err != nil {
+		return err
+	}
+	return g2.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_search_vector ON %s USING GIN (search_vector)", table, table)).Error
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Subsystem used to define the connection pool metrics:
+const poolMetricsSubsystem = "db_pool"
+
+// PoolMetricsCollector exports the database/sql connection pool statistics of a SessionFactory.
+// The statistics are read from sql.DB.Stats() every time the metrics are scraped.
+type PoolMetricsCollector struct {
+	connection SessionFactory
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+var _ prometheus.Collector = &PoolMetricsCollector{}
+
+// NewPoolMetricsCollector returns a collector for the connection pool behind the given SessionFactory.
+func NewPoolMetricsCollector(connection SessionFactory) *PoolMetricsCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("", poolMetricsSubsystem, name), help, nil, nil)
+	}
+	return &PoolMetricsCollector{
+		connection:        connection,
+		maxOpen:           desc("max_open_connections", "Maximum number of open connections to the database."),
+		open:              desc("open_connections", "The number of established connections both in use and idle."),
+		inUse:             desc("in_use_connections", "The number of connections currently in use."),
+		idle:              desc("idle_connections", "The number of idle connections."),
+		waitCount:         desc("wait_count_total", "The total number of connections waited for."),
+		waitDuration:      desc("wait_duration_seconds_total", "The total time blocked waiting for a new connection."),
+		maxIdleClosed:     desc("max_idle_closed_total", "The total number of connections closed due to the max idle connections limit."),
+		maxIdleTimeClosed: desc("max_idle_time_closed_total", "The total number of connections closed due to the max connection idle time."),
+		maxLifetimeClosed: desc("max_lifetime_closed_total", "The total number of connections closed due to the max connection lifetime."),
+	}
+}
+
+func (c *PoolMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+func (c *PoolMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.connection == nil || c.connection.DirectDB() == nil {
+		return
+	}
+	stats := c.connection.DirectDB().Stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
+
+// RegisterPoolMetrics registers the connection pool collector for the given SessionFactory.
+// Registering again, e.g. when the metrics server is restarted, keeps the first collector.
+func RegisterPoolMetrics(connection SessionFactory) error {
+	err := prometheus.Register(NewPoolMetricsCollector(connection))
+	if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	dbContext "github.com/openshift-online/rh-trex/pkg/db/db_context"
+	"github.com/openshift-online/rh-trex/pkg/logger"
+)
+
+// retryBackoff is the base wait between attempts, it grows with every attempt and is jittered
+// so that the transactions that conflicted don't run into each other again.
+var retryBackoff = 25 * time.Millisecond
+
+// waitBeforeRetry waits for the backoff of the given attempt and reports whether to retry,
+// false when ctx is done in the meantime.
+func waitBeforeRetry(ctx context.Context, attempt int) bool {
+	wait := time.Duration(attempt) * retryBackoff
+	wait += time.Duration(rand.Int63n(int64(retryBackoff)))
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(wait):
+		return true
+	}
+}
+
+// RetryTransaction is the HTTP middleware of the routes writing resources, mounted after TransactionMiddleware, that runs the
+// handler again, up to attempts times in total, while the request transaction fails on a transient error
+// (see IsRetryable): marked for rollback with one, or failing to commit with one. Postgres requires the
+// whole transaction to be restarted, so the failed request transaction is rolled back, which releases the
+// advisory locks of the attempt, and each attempt runs in a new request transaction. The transaction of
+// the last attempt is committed here, and the response is only written once no more attempts are made.
+// Only use it for handlers without side effects outside of the database, the ones of the failed attempts are not undone.
+func RetryTransaction(next http.Handler, connection SessionFactory, attempts int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := dbContext.ActiveTransaction(r.Context()); !ok || attempts <= 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log := logger.NewOCMLogger(r.Context())
+		for attempt := 1; ; attempt++ {
+			response := newBufferedResponse()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(response, r)
+
+			err := commitAttempt(r.Context())
+			if attempt >= attempts || !IsRetryable(err) || !waitBeforeRetry(r.Context(), attempt) {
+				response.writeTo(w)
+				return
+			}
+			if tx, ok := dbContext.ActiveTransaction(r.Context()); ok {
+				_ = tx.Rollback()
+			}
+			if err := restartTransaction(r.Context(), connection); err != nil {
+				log.Extra("error", err.Error()).Error("Could not restart transaction")
+				response.writeTo(w)
+				return
+			}
+			log.Infof("Retrying request after a transient database error (attempt %d of %d): %v", attempt, attempts, err)
+		}
+	})
+}
+
+// commitAttempt commits the request transaction unless it is marked for rollback, and returns the error
+// which failed the attempt, if any: the rollback error or the commit error.
+// A transaction marked for rollback is left to TransactionMiddleware, unless the attempt is retried.
+func commitAttempt(ctx context.Context) error {
+	tx, ok := dbContext.ActiveTransaction(ctx)
+	if !ok {
+		return nil
+	}
+	if tx.MarkedForRollback() {
+		return tx.RollbackError()
+	}
+	if err := tx.Commit(); err != nil {
+		logger.NewOCMLogger(ctx).Extra("error", err.Error()).Error("Could not commit transaction")
+		return err
+	}
+	return nil
+}
+
+// bufferedResponse holds a response until it is known whether the request is retried.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) writeTo(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(b.status)
+	_, _ = w.Write(b.body.Bytes())
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift-online/rh-trex/pkg/errors"
+	"github.com/yaacov/tree-search-language/pkg/tsl"
+)
+
+// SearchFieldType is the type of a searchable field, which decides the values it can be compared with
+// and, unless the field gives its own, the operators comparing it
+type SearchFieldType string
+
+const (
+	SearchString  SearchFieldType = "string"
+	SearchNumber  SearchFieldType = "number"
+	SearchBoolean SearchFieldType = "boolean"
+	// SearchTime fields are compared with RFC 3339 times, such as '2024-05-01T12:00:00Z', or dates, such as '2024-05-01'
+	SearchTime SearchFieldType = "time"
+	// SearchProperties is the type of the properties column, only searched by the keys of its properties as
+	// `properties.<key>`, see api.Properties
+	SearchProperties SearchFieldType = "properties"
+)
+
+var (
+	equalityOperators   = []string{tsl.EqOp, tsl.NotEqOp, tsl.InOp, tsl.NotInOp, tsl.IsNilOp, tsl.IsNotNilOp}
+	comparisonOperators = append([]string{tsl.LtOp, tsl.LteOp, tsl.GtOp, tsl.GteOp, tsl.BetweenOp, tsl.NotBetweenOp}, equalityOperators...)
+
+	// searchOperators are the operators of the fields of each type, unless the fields give their own
+	searchOperators = map[SearchFieldType][]string{
+		SearchString:  append([]string{tsl.LikeOp, tsl.ILikeOp}, comparisonOperators...),
+		SearchNumber:  comparisonOperators,
+		SearchBoolean: equalityOperators,
+		SearchTime:    comparisonOperators,
+	}
+
+	// operatorNames are the names of the operators in the searches, for the error messages
+	operatorNames = map[string]string{
+		tsl.EqOp: "=", tsl.NotEqOp: "!=", tsl.LtOp: "<", tsl.LteOp: "<=", tsl.GtOp: ">", tsl.GteOp: ">=",
+		tsl.LikeOp: "like", tsl.ILikeOp: "ilike", tsl.RegexOp: "~=", tsl.NotRegexOp: "~!",
+		tsl.InOp: "in", tsl.NotInOp: "not in", tsl.BetweenOp: "between", tsl.NotBetweenOp: "not between",
+		tsl.IsNilOp: "is null", tsl.IsNotNilOp: "is not null",
+	}
+)
+
+// SearchField is a field the searches and the orderings of a kind can use
+//
+//	Column is the column of the field, its name when empty.
+//	Type is the type of the field, see SearchFieldType.
+//	Operators are the TSL operators comparing the field, such as tsl.EqOp, the ones of its type when nil.
+type SearchField struct {
+	Column    string
+	Type      SearchFieldType
+	Operators []string
+}
+
+// SearchSchema is the fields the searches and the orderings of a kind can use, by their API names, the other
+// columns of the kind being rejected. The fields of the related resources are named <table>.<field>, which is
+// how the searches name them once their relation is joined, unless the related kind is in Related.
+// See FieldNameWalk and ArgsToOrderBy.
+type SearchSchema struct {
+	// Table is the table of the kind, which the searches qualify its fields with
+	Table  string
+	Fields map[string]SearchField
+	// Related are the schemas of the related kinds, by their tables, whose fields the searches can use
+	Related map[string]*SearchSchema
+}
+
+// schemaOf returns the schema of the field the name qualifies with a related table, the schema itself otherwise
+func (s *SearchSchema) schemaOf(name string) *SearchSchema {
+	for table, related := range s.Related {
+		if strings.HasPrefix(name, table+".") {
+			return related
+		}
+	}
+	return s
+}
+
+// lookup returns the qualified column of the field the search or the ordering names, as qualified as the name
+func (s *SearchSchema) lookup(name string) (string, SearchField, bool) {
+	if related := s.schemaOf(name); related != s {
+		return related.lookup(name)
+	}
+	qualifier := ""
+	if strings.HasPrefix(name, s.Table+".") {
+		qualifier, name = s.Table+".", strings.TrimPrefix(name, s.Table+".")
+	}
+	field, ok := s.Fields[name]
+	if !ok {
+		return "", field, false
+	}
+	column := field.Column
+	if column == "" {
+		column = name
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 && field.Column != "" {
+		// the related fields mapped to another column keep the table of the relation
+		column = name[:i+1] + field.Column
+	}
+	return qualifier + column, field, true
+}
+
+// unknownField is the error of the names which aren't fields of the schema, listing the ones which are
+func (s *SearchSchema) unknownField(name string) *errors.ServiceError {
+	if related := s.schemaOf(name); related != s {
+		return related.unknownField(name)
+	}
+	names := make([]string, 0, len(s.Fields))
+	for fieldName := range s.Fields {
+		names = append(names, fieldName)
+	}
+	sort.Strings(names)
+	return errors.BadRequest("%s is not a valid field name, the fields are %s",
+		strings.TrimPrefix(name, s.Table+"."), strings.Join(names, ", "))
+}
+
+// Column returns the column of the field the search, the ordering or the aggregation names, the properties
+// being only searched by their keys
+func (s *SearchSchema) Column(name string) (string, *errors.ServiceError) {
+	column, field, ok := s.lookup(name)
+	if !ok {
+		return "", s.unknownField(name)
+	}
+	if field.Type == SearchProperties {
+		return "", errors.BadRequest("%s can only be searched by the keys of its properties, as %s.<key>",
+			strings.TrimPrefix(name, s.Table+"."), strings.TrimPrefix(name, s.Table+"."))
+	}
+	return column, nil
+}
+
+// CheckField checks that the field the fields of a list name, a property of which as <field>.<key>, is a field of the schema
+func (s *SearchSchema) CheckField(name string) *errors.ServiceError {
+	name = strings.Split(name, ".")[0]
+	if _, _, ok := s.lookup(name); !ok {
+		return s.unknownField(name)
+	}
+	return nil
+}
+
+// allowsProperties returns true if the properties column the search names, possibly qualified, is a field of the schema
+func (s *SearchSchema) allowsProperties(column string) bool {
+	_, field, ok := s.lookup(column)
+	return ok && field.Type == SearchProperties
+}
+
+// checkComparison checks that the comparison of a field is one of its operators, with values of its type
+func (s *SearchSchema) checkComparison(n tsl.Node) *errors.ServiceError {
+	name := n.Left.(tsl.Node).Left.(string)
+	if related := s.schemaOf(name); related != s {
+		return related.checkComparison(n)
+	}
+	_, field, ok := s.lookup(name)
+	if !ok {
+		return s.unknownField(name)
+	}
+	if field.Type == SearchProperties {
+		// the properties are compared by their keys, see Column
+		return nil
+	}
+	name = strings.TrimPrefix(name, s.Table+".")
+
+	operators := field.Operators
+	if operators == nil {
+		operators = searchOperators[field.Type]
+	}
+	allowed := false
+	for _, operator := range operators {
+		allowed = allowed || operator == n.Func
+	}
+	if !allowed {
+		names := make([]string, 0, len(operators))
+		for _, operator := range operators {
+			names = append(names, operatorNames[operator])
+		}
+		return errors.BadRequest("The operator %s can't be used on %s, its operators are %s",
+			operatorNames[n.Func], name, strings.Join(names, ", "))
+	}
+
+	var values []tsl.Node
+	switch r := n.Right.(type) {
+	case []tsl.Node:
+		values = r
+	case tsl.Node:
+		values = []tsl.Node{r}
+		if r.Func == tsl.ArrayOp {
+			values, _ = r.Right.([]tsl.Node)
+		}
+	}
+	for _, value := range values {
+		if (value.Func == tsl.NumberOp || value.Func == tsl.StringOp) && !field.accepts(value) {
+			return errors.BadRequest("%s can't be compared with %s, it is a %s%s", name, literal(value), field.Type, typeHint[field.Type])
+		}
+	}
+	return nil
+}
+
+// typeHint describes the values of the types which aren't obvious, for the error messages
+var typeHint = map[SearchFieldType]string{
+	SearchBoolean: ", compared with 'true' or 'false'",
+	SearchTime:    ", compared with RFC 3339 times such as '2024-05-01T12:00:00Z' or dates such as '2024-05-01'",
+}
+
+// accepts returns true if the literal is a value of the type of the field
+func (f SearchField) accepts(value tsl.Node) bool {
+	if value.Func == tsl.NumberOp {
+		return f.Type == SearchNumber
+	}
+	s, _ := value.Left.(string)
+	switch f.Type {
+	case SearchString:
+		return true
+	case SearchBoolean:
+		return s == "true" || s == "false"
+	case SearchTime:
+		if _, err := time.Parse(time.RFC3339, s); err == nil {
+			return true
+		}
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	}
+	return false
+}
+
+// literal formats a literal of a search as it was written
+func literal(value tsl.Node) string {
+	if value.Func == tsl.StringOp {
+		return fmt.Sprintf("'%v'", value.Left)
+	}
+	return fmt.Sprintf("%v", value.Left)
+}
+
+// isComparison returns true if the node compares a field
+func isComparison(n tsl.Node) bool {
+	if _, ok := operatorNames[n.Func]; !ok {
+		return false
+	}
+	l, ok := n.Left.(tsl.Node)
+	return ok && l.Func == tsl.IdentOp
+}
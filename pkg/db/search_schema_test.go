@@ -0,0 +1,102 @@
+package db
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/yaacov/tree-search-language/pkg/tsl"
+	sqlFilter "github.com/yaacov/tree-search-language/pkg/walkers/sql"
+)
+
+func TestSearchSchema(t *testing.T) {
+	RegisterTestingT(t)
+
+	schema := &SearchSchema{
+		Table: "dinosaurs",
+		Fields: map[string]SearchField{
+			"id":          {Type: SearchString},
+			"name":        {Column: "species", Type: SearchString, Operators: []string{tsl.EqOp, tsl.InOp}},
+			"teeth":       {Type: SearchNumber},
+			"extinct":     {Type: SearchBoolean},
+			"created_at":  {Type: SearchTime},
+			"properties":  {Type: SearchProperties},
+			"fossils.age": {Type: SearchNumber},
+		},
+	}
+
+	// the searches are qualified with the tables, the fields are mapped to their columns
+	tests := []struct {
+		search string
+		sql    string
+		error  string
+	}{
+		{search: "dinosaurs.name in ('rex', 'stego')", sql: "dinosaurs.species IN (?,?)"},
+		{search: "dinosaurs.teeth between 10 and 20 and dinosaurs.extinct = 'true'", sql: "(dinosaurs.teeth BETWEEN ? AND ? AND dinosaurs.extinct = ?)"},
+		{search: "dinosaurs.created_at > '2024-05-01' or fossils.age < 65", sql: "(dinosaurs.created_at > ? OR fossils.age < ?)"},
+		{search: "dinosaurs.properties.color = 'red'", sql: "dinosaurs.properties #>> '{color}' = ?"},
+		{
+			search: "dinosaurs.org_id = 'abc'",
+			error:  "rh-trex-21: org_id is not a valid field name, the fields are created_at, extinct, fossils.age, id, name, properties, teeth",
+		},
+		{search: "dinosaurs.name like 'rex%'", error: "rh-trex-21: The operator like can't be used on name, its operators are =, in"},
+		{search: "dinosaurs.teeth = 'many'", error: "rh-trex-21: teeth can't be compared with 'many', it is a number"},
+		{search: "dinosaurs.id > 3", error: "rh-trex-21: id can't be compared with 3, it is a string"},
+		{search: "dinosaurs.extinct = 'yes'", error: "rh-trex-21: extinct can't be compared with 'yes', it is a boolean, compared with 'true' or 'false'"},
+		{search: "dinosaurs.created_at < 'yesterday'", error: "rh-trex-21: created_at can't be compared with 'yesterday', it is a time, compared with RFC 3339 times such as '2024-05-01T12:00:00Z' or dates such as '2024-05-01'"},
+		{search: "dinosaurs.properties = 'red'", error: "rh-trex-21: properties can only be searched by the keys of its properties, as properties.<key>"},
+	}
+	for _, test := range tests {
+		tslTree, err := tsl.ParseTSL(test.search)
+		Expect(err).ToNot(HaveOccurred())
+		tslTree, serviceErr := FieldNameWalk(tslTree, map[string]string{}, schema)
+		if test.error != "" {
+			Expect(serviceErr).To(HaveOccurred(), test.search)
+			Expect(serviceErr.Error()).To(Equal(test.error))
+			continue
+		}
+		Expect(serviceErr).ToNot(HaveOccurred(), test.search)
+		sqlizer, err := sqlFilter.Walk(tslTree)
+		Expect(err).ToNot(HaveOccurred())
+		sql, _, err := sqlizer.ToSql()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sql).To(Equal(test.sql))
+	}
+
+	orderBy, serviceErr := ArgsToOrderBy([]string{"name desc", "created_at"}, map[string]string{}, schema)
+	Expect(serviceErr).ToNot(HaveOccurred())
+	Expect(orderBy).To(Equal([]string{"species desc", "created_at asc"}))
+	_, serviceErr = ArgsToOrderBy([]string{"org_id"}, map[string]string{}, schema)
+	Expect(serviceErr).To(HaveOccurred())
+	Expect(serviceErr.Error()).To(HavePrefix("rh-trex-21: org_id is not a valid field name"))
+	_, serviceErr = ArgsToOrderBy([]string{"name sideways"}, map[string]string{}, schema)
+	Expect(serviceErr.Error()).To(Equal("rh-trex-21: bad order value 'name sideways'"))
+
+	// the fields of the related kinds are checked by their schemas
+	nests := &SearchSchema{
+		Table:   "nests",
+		Fields:  map[string]SearchField{"location": {Type: SearchString}},
+		Related: map[string]*SearchSchema{"dinosaurs": schema},
+	}
+	tslTree, err := tsl.ParseTSL("nests.location = 'cave' and dinosaurs.name = 'rex'")
+	Expect(err).ToNot(HaveOccurred())
+	tslTree, serviceErr = FieldNameWalk(tslTree, map[string]string{}, nests)
+	Expect(serviceErr).ToNot(HaveOccurred())
+	sqlizer, err := sqlFilter.Walk(tslTree)
+	Expect(err).ToNot(HaveOccurred())
+	sql, _, err := sqlizer.ToSql()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(sql).To(Equal("(nests.location = ? AND dinosaurs.species = ?)"))
+	tslTree, err = tsl.ParseTSL("dinosaurs.teeth = 'many'")
+	Expect(err).ToNot(HaveOccurred())
+	_, serviceErr = FieldNameWalk(tslTree, map[string]string{}, nests)
+	Expect(serviceErr.Error()).To(Equal("rh-trex-21: teeth can't be compared with 'many', it is a number"))
+	tslTree, err = tsl.ParseTSL("dinosaurs.org_id = 'abc'")
+	Expect(err).ToNot(HaveOccurred())
+	_, serviceErr = FieldNameWalk(tslTree, map[string]string{}, nests)
+	Expect(serviceErr.Error()).To(HavePrefix("rh-trex-21: org_id is not a valid field name, the fields are created_at"))
+
+	// the kinds without a schema can use any field
+	orderBy, serviceErr = ArgsToOrderBy([]string{"org_id"}, map[string]string{}, nil)
+	Expect(serviceErr).ToNot(HaveOccurred())
+	Expect(orderBy).To(Equal([]string{"org_id asc"}))
+}
@@ -0,0 +1,353 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openshift-online/rh-trex/pkg/config"
+	"github.com/openshift-online/rh-trex/pkg/logger"
+)
+
+// SessionAdvisoryLockFactory provides PostgreSQL session-level advisory locks.
+//
+//	conn := db.Conn()                   # pin a connection of the pool
+//	select pg_advisory_lock(key)        # obtain the lock (blocking)
+//	select pg_advisory_unlock(key)      # release the lock
+//	conn.Close()                        # return the connection to the pool
+//
+// Unlike AdvisoryLockFactory no transaction is kept open while the lock is held, so the lock can be
+// held across request transactions or by code running outside of one. Each held lock pins a connection
+// of the pool besides the one of the request transaction, size the pool for both before using it in
+// request handling.
+//
+// The locks are re-entrant for callers sharing a context scoped with WithAdvisoryLockScope (the
+// request contexts created by NewContext are): a nested NewAdvisoryLock for the same (id, lockType)
+// reuses the lock held by the outer call and returns the same owner id, the lock is released when
+// the outermost caller unlocks it. Without a scope every call obtains its own lock.
+//
+// The scoped locks are owned by their scope rather than by the factory which obtained them, service
+// locators create a new factory for every service so nested service calls unlock through another one.
+type SessionAdvisoryLockFactory struct {
+	connection  SessionFactory
+	locks       map[string]*sessionAdvisoryLock
+	mutex       sync.RWMutex
+	lockTimeout time.Duration
+	legacyKeys  bool
+}
+
+var _ LockFactory = &SessionAdvisoryLockFactory{}
+
+// NewSessionAdvisoryLockFactory returns a new factory of session-level advisory locks.
+// Timeouts and lock keys are configured as for NewAdvisoryLockFactory.
+func NewSessionAdvisoryLockFactory(connection SessionFactory, config *config.DatabaseConfig) *SessionAdvisoryLockFactory {
+	f := &SessionAdvisoryLockFactory{
+		connection: connection,
+		locks:      make(map[string]*sessionAdvisoryLock),
+	}
+	if config != nil {
+		f.lockTimeout = config.AdvisoryLockTimeout
+		f.legacyKeys = config.AdvisoryLockLegacyKeys
+	}
+	return f
+}
+
+func (f *SessionAdvisoryLockFactory) NewAdvisoryLock(ctx context.Context, id string, lockType LockType) (string, error) {
+	owner, _, err := f.obtain(ctx, id, lockType, true)
+	return owner, err
+}
+
+func (f *SessionAdvisoryLockFactory) NewNonBlockingLock(ctx context.Context, id string, lockType LockType) (string, bool, error) {
+	return f.obtain(ctx, id, lockType, false)
+}
+
+func (f *SessionAdvisoryLockFactory) obtain(ctx context.Context, id string, lockType LockType, blocking bool) (string, bool, error) {
+	log := logger.NewOCMLogger(ctx)
+
+	scope, scoped := advisoryLockScopeFrom(ctx)
+	if scoped {
+		if lock := scope.reenter(id, lockType); lock != nil {
+			log.V(4).Info(fmt.Sprintf("Re-entered session advisory lock id=%s type=%s - owner=%s", id, lockType, lock.uuid))
+			return lock.uuid, true, nil
+		}
+	}
+
+	lock, err := f.newLock(ctx, id, lockType)
+	if err != nil {
+		return "", false, err
+	}
+
+	acquired := true
+	if blocking {
+		err = lock.lock(ctx, f.lockTimeout)
+	} else {
+		acquired, err = lock.tryLock(ctx)
+	}
+	if err != nil {
+		status := "lock error"
+		if errors.Is(err, ErrAdvisoryLockTimeout) {
+			status = "timeout"
+		}
+		UpdateAdvisoryLockCountMetric(lockType, status)
+		UpdateAdvisoryLockDurationMetric(lockType, status, lock.startTime)
+		log.Error(fmt.Sprintf("error obtaining the session advisory lock for id %s type %s, %v", id, lockType, err))
+		// the outcome of the lock query is unknown, closing the session is the only way to be sure
+		// the lock is not left behind on a pooled connection.
+		lock.discard()
+		return "", false, fmt.Errorf("error obtaining the session advisory lock for id %s type %s: %w", id, lockType, err)
+	}
+	if !acquired {
+		_ = lock.conn.Close()
+		return "", false, nil
+	}
+
+	log.V(4).Info(fmt.Sprintf("Locked session advisory lock id=%s type=%s - owner=%s", id, lockType, lock.uuid))
+	if scoped {
+		lock.scope = scope
+		scope.add(lock)
+	} else {
+		f.mutex.Lock()
+		f.locks[lock.uuid] = lock
+		f.mutex.Unlock()
+	}
+	heldLocks.add(lock.uuid, id, lockType)
+	return lock.uuid, true, nil
+}
+
+func (f *SessionAdvisoryLockFactory) newLock(ctx context.Context, id string, lockType LockType) (*sessionAdvisoryLock, error) {
+	conn, err := f.connection.DirectDB().Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionAdvisoryLock{
+		conn:       conn,
+		uuid:       uuid.New().String(),
+		id:         id,
+		lockType:   lockType,
+		legacyKeys: f.legacyKeys,
+		depth:      1,
+		startTime:  time.Now(),
+	}, nil
+}
+
+// Unlock releases the lock owned by uuid once every re-entrant caller has unlocked it.
+func (f *SessionAdvisoryLockFactory) Unlock(ctx context.Context, uuid string) {
+	log := logger.NewOCMLogger(ctx)
+
+	if uuid == "" {
+		return
+	}
+
+	lock, ok := f.owned(ctx, uuid)
+	if !ok {
+		log.V(4).Info(fmt.Sprintf("Caller not lock owner. Owner %s", uuid))
+		return
+	}
+
+	if lock.scope != nil && !lock.scope.leave(lock) {
+		log.V(4).Info(fmt.Sprintf("Left re-entrant session advisory lock id=%s type=%s - owner=%s", lock.id, lock.lockType, uuid))
+		return
+	}
+
+	// the lock is released once the request transaction is resolved, not before its writes are committed.
+	afterTransaction(ctx, func() {
+		if lock.scope != nil && !lock.scope.release(lock) {
+			// re-entered before the transaction was resolved, or already released.
+			return
+		}
+		if err := lock.unlock(); err != nil {
+			UpdateAdvisoryLockCountMetric(lock.lockType, "unlock error")
+			log.Extra("lockID", lock.id).Extra("owner", uuid).Error(fmt.Sprintf("Could not unlock, %v", err))
+		}
+
+		UpdateAdvisoryLockCountMetric(lock.lockType, "OK")
+		UpdateAdvisoryLockDurationMetric(lock.lockType, "OK", lock.startTime)
+
+		log.V(4).Info(fmt.Sprintf("Unlocked session advisory lock id=%s type=%s - owner=%s", lock.id, lock.lockType, uuid))
+
+		if lock.scope == nil {
+			f.mutex.Lock()
+			delete(f.locks, uuid)
+			f.mutex.Unlock()
+		}
+		heldLocks.remove(uuid)
+	})
+}
+
+// owned returns the lock owned by uuid, held in the scope of the context or, without one, by the factory.
+func (f *SessionAdvisoryLockFactory) owned(ctx context.Context, uuid string) (*sessionAdvisoryLock, bool) {
+	if scope, ok := advisoryLockScopeFrom(ctx); ok {
+		if lock := scope.owned(uuid); lock != nil {
+			return lock, true
+		}
+	}
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	lock, ok := f.locks[uuid]
+	return lock, ok
+}
+
+// sessionAdvisoryLock is a session-level advisory lock held on a pinned connection.
+type sessionAdvisoryLock struct {
+	conn       *sql.Conn
+	uuid       string
+	id         string
+	lockType   LockType
+	legacyKeys bool
+	startTime  time.Time
+	// depth counts the re-entrant callers, guarded by the scope's mutex.
+	depth int
+	scope *advisoryLockScope
+}
+
+// lock calls select pg_advisory_lock(key) on the pinned connection.
+// it waits at most timeout (when > 0) and returns ErrAdvisoryLockTimeout when the wait expires
+// or ctx is done before the lock is obtained.
+func (l *sessionAdvisoryLock) lock(ctx context.Context, timeout time.Duration) error {
+	lockCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	query, args := l.query("pg_advisory_lock")
+	if _, err := l.conn.ExecContext(lockCtx, query, args...); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: %w", ErrAdvisoryLockTimeout, ctx.Err())
+		}
+		if lockCtx.Err() != nil {
+			return fmt.Errorf("%w after %s", ErrAdvisoryLockTimeout, timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+func (l *sessionAdvisoryLock) tryLock(ctx context.Context) (bool, error) {
+	var acquired bool
+	query, args := l.query("pg_try_advisory_lock")
+	err := l.conn.QueryRowContext(ctx, query, args...).Scan(&acquired)
+	return acquired, err
+}
+
+// unlock releases the lock and returns the connection to the pool.
+// if the lock can't be released the connection is closed instead, which releases the lock too.
+func (l *sessionAdvisoryLock) unlock() error {
+	var released bool
+	query, args := l.query("pg_advisory_unlock")
+	// the caller's context may already be done, the lock must be released regardless.
+	err := l.conn.QueryRowContext(context.Background(), query, args...).Scan(&released)
+	if err == nil && !released {
+		err = errors.New("session advisory lock was not held by the connection")
+	}
+	if err != nil {
+		l.discard()
+		return err
+	}
+	return l.conn.Close()
+}
+
+// discard closes the pinned connection instead of returning it to the pool,
+// postgres releases every session-level lock of a closed session.
+func (l *sessionAdvisoryLock) discard() {
+	_ = l.conn.Raw(func(driverConn interface{}) error {
+		return driver.ErrBadConn
+	})
+	_ = l.conn.Close()
+}
+
+// query returns the call of the given pg_advisory_* function for this lock.
+// the pinned connection is used directly, so the gorm placeholders are turned into postgres ones.
+func (l *sessionAdvisoryLock) query(function string) (string, []interface{}) {
+	_, args := advisoryLockKeys(l.id, l.lockType, l.legacyKeys)
+	placeholders := make([]string, len(args))
+	for i := range args {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return fmt.Sprintf("select %s(%s)", function, strings.Join(placeholders, ", ")), args
+}
+
+type advisoryLockScopeKey struct{}
+
+// advisoryLockScope tracks the session advisory locks obtained by callers sharing a context.
+type advisoryLockScope struct {
+	mutex sync.Mutex
+	locks map[string]*sessionAdvisoryLock
+}
+
+// WithAdvisoryLockScope returns a context in which session advisory locks are re-entrant.
+// A context that already has a scope is returned as is.
+func WithAdvisoryLockScope(ctx context.Context) context.Context {
+	if _, ok := advisoryLockScopeFrom(ctx); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, advisoryLockScopeKey{}, &advisoryLockScope{locks: map[string]*sessionAdvisoryLock{}})
+}
+
+func advisoryLockScopeFrom(ctx context.Context) (*advisoryLockScope, bool) {
+	scope, ok := ctx.Value(advisoryLockScopeKey{}).(*advisoryLockScope)
+	return scope, ok
+}
+
+func scopeKey(id string, lockType LockType) string {
+	return string(lockType) + "\x00" + id
+}
+
+// reenter returns the lock held in the scope for (id, lockType), if any, counting one more caller.
+func (s *advisoryLockScope) reenter(id string, lockType LockType) *sessionAdvisoryLock {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	lock, ok := s.locks[scopeKey(id, lockType)]
+	if !ok {
+		return nil
+	}
+	lock.depth++
+	return lock
+}
+
+// owned returns the lock of the scope owned by uuid, if any.
+func (s *advisoryLockScope) owned(uuid string) *sessionAdvisoryLock {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, lock := range s.locks {
+		if lock.uuid == uuid {
+			return lock
+		}
+	}
+	return nil
+}
+
+func (s *advisoryLockScope) add(lock *sessionAdvisoryLock) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.locks[scopeKey(lock.id, lock.lockType)] = lock
+}
+
+// leave counts one caller out of the lock and reports whether it was the last one.
+// the lock stays in the scope until it is released, callers may re-enter it in the meantime.
+func (s *advisoryLockScope) leave(lock *sessionAdvisoryLock) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	lock.depth--
+	return lock.depth == 0
+}
+
+// release removes the lock from the scope and reports whether it must be released,
+// that is when no caller re-entered it since the last one left.
+func (s *advisoryLockScope) release(lock *sessionAdvisoryLock) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := scopeKey(lock.id, lock.lockType)
+	if lock.depth > 0 || s.locks[key] != lock {
+		return false
+	}
+	delete(s.locks, key)
+	return true
+}
@@ -3,43 +3,189 @@ package db
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/jinzhu/inflection"
+	"github.com/lib/pq"
+	"github.com/openshift-online/rh-trex/pkg/db/migrations"
 	"github.com/openshift-online/rh-trex/pkg/errors"
 	"github.com/yaacov/tree-search-language/pkg/tsl"
 	"gorm.io/gorm"
 )
 
-// Check if a field name starts with properties.
-func startsWithProperties(s string) bool {
-	return strings.HasPrefix(s, "properties.")
+// PropertiesColumn is the JSONB column of the resources holding free form properties, see api.Properties
+const PropertiesColumn = "properties"
+
+var propertyKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// TextSearchConfig is the text search configuration of the full-text queries, the one the tsvector columns they
+// search are built with, see migrations.AddTextSearchColumn
+const TextSearchConfig = migrations.TextSearchConfig
+
+// TextSearchMatch returns the predicate of the records whose tsvector column matches the full-text query,
+// a web search style query such as `long neck -stegosaurus` or `"armored tail"`, which is its single value
+func TextSearchMatch(column string) string {
+	return fmt.Sprintf("%s @@ websearch_to_tsquery('%s', ?)", column, TextSearchConfig)
+}
+
+// TextSearchRank returns the ordering of the records by the relevance of their tsvector column to the full-text
+// query, the most relevant first. The order clauses have no values, the query is quoted in it.
+func TextSearchRank(column string, query string) string {
+	return fmt.Sprintf("ts_rank(%s, websearch_to_tsquery('%s', %s)) desc", column, TextSearchConfig, pq.QuoteLiteral(query))
+}
+
+// propertyPath splits an identifier referring to a property, `properties.<path>` or `<table>.properties.<path>`,
+// into the qualified properties column and the path of the property. The path is a single key, two keys
+// `properties.<key>.<key>` or, the identifiers having at most three parts, a quoted dot separated path
+// such as `properties."address.geo.lat"`. The column is empty when the identifier doesn't refer to a property.
+func propertyPath(name string) (column string, path []string, err *errors.ServiceError) {
+	var rest string
+	switch {
+	case strings.HasPrefix(name, PropertiesColumn+"."):
+		column, rest = PropertiesColumn, strings.TrimPrefix(name, PropertiesColumn+".")
+	case strings.Contains(name, "."+PropertiesColumn+"."):
+		i := strings.Index(name, "."+PropertiesColumn+".")
+		if strings.Contains(name[:i], ".") {
+			return "", nil, nil
+		}
+		column, rest = name[:i+len(PropertiesColumn)+1], name[i+len(PropertiesColumn)+2:]
+	default:
+		return "", nil, nil
+	}
+
+	for _, part := range strings.Split(rest, ".") {
+		path = append(path, strings.Trim(part, `"`))
+	}
+	for _, key := range path {
+		if !propertyKeyPattern.MatchString(key) {
+			return "", nil, errors.BadRequest("%s is not a valid property name, its keys must be alphanumeric characters, '-' or '_'", name)
+		}
+	}
+	return column, path, nil
+}
+
+// propertyExpression returns the SQL expression of the property at path within the column: as text for the
+// `text` type, as JSONB for the `jsonb` type, and for the `numeric` and `boolean` types, when the property has
+// the JSON type matching it and NULL otherwise, so that the other properties never fail the cast.
+func propertyExpression(column string, path []string, sqlType string) string {
+	pathLiteral := fmt.Sprintf("'{%s}'", strings.Join(path, ","))
+	switch sqlType {
+	case "jsonb":
+		return fmt.Sprintf("%s #> %s", column, pathLiteral)
+	case "numeric":
+		return fmt.Sprintf("(CASE WHEN jsonb_typeof(%s #> %s) = 'number' THEN (%s #>> %s)::numeric END)", column, pathLiteral, column, pathLiteral)
+	case "boolean":
+		return fmt.Sprintf("(CASE WHEN jsonb_typeof(%s #> %s) = 'boolean' THEN (%s #>> %s)::boolean END)", column, pathLiteral, column, pathLiteral)
+	default:
+		return fmt.Sprintf("%s #>> %s", column, pathLiteral)
+	}
 }
 
 // hasProperty return true if node has a property identifier on left hand side.
 func hasProperty(n tsl.Node) bool {
 	// Get the left side operator.
 	l, ok := n.Left.(tsl.Node)
-	if !ok {
+	if !ok || l.Func != tsl.IdentOp {
 		return false
 	}
 
-	// If left side hand is not a `properties` identifier, return.
-	if l.Func != tsl.IdentOp || !startsWithProperties(l.Left.(string)) {
+	name, ok := l.Left.(string)
+	if !ok {
 		return false
 	}
+	column, _, err := propertyPath(name)
+	return column != "" && err == nil
+}
+
+// propertyType returns the SQL type the property is compared as, given the right hand side of the comparison:
+// numbers are compared as numerics, the strings 'true' and 'false' as booleans and anything else as text.
+func propertyType(n tsl.Node) string {
+	switch n.Func {
+	case tsl.IsNilOp, tsl.IsNotNilOp:
+		return "jsonb"
+	case tsl.LikeOp, tsl.ILikeOp, tsl.RegexOp, tsl.NotRegexOp:
+		return "text"
+	}
+
+	r, ok := n.Right.(tsl.Node)
+	if !ok {
+		return "text"
+	}
+	literals := []tsl.Node{r}
+	if r.Func == tsl.ArrayOp {
+		literals, _ = r.Right.([]tsl.Node)
+	}
+	if len(literals) == 0 {
+		return "text"
+	}
 
-	return true
+	numeric, boolean := true, n.Func == tsl.EqOp || n.Func == tsl.NotEqOp
+	for _, literal := range literals {
+		numeric = numeric && literal.Func == tsl.NumberOp
+		boolean = boolean && literal.Func == tsl.StringOp && (literal.Left == "true" || literal.Left == "false")
+	}
+	switch {
+	case numeric:
+		return "numeric"
+	case boolean:
+		return "boolean"
+	}
+	return "text"
 }
 
-// getField gets the sql field associated with a name.
-func getField(name string, disallowedFields map[string]string) (field string, err *errors.ServiceError) {
+// propertiesNodeConverter converts a node with a properties identifier to a node comparing the property,
+// typed after the right hand side of the comparison.
+//
+// For example, it will convert:
+// ( properties.<name> > 3 ) to
+// ( (CASE WHEN jsonb_typeof(properties #> '{<name>}') = 'number' THEN (properties #>> '{<name>}')::numeric END) > 3 )
+// and the existence checks:
+// ( properties.<name> IS NOT NULL ) to
+// ( properties #> '{<name>}' IS NOT NULL )
+func propertiesNodeConverter(n tsl.Node, disallowedFields map[string]string, schema *SearchSchema) (tsl.Node, *errors.ServiceError) {
+	name := n.Left.(tsl.Node).Left.(string)
+	column, path, err := propertyPath(name)
+	if err != nil {
+		return n, err
+	}
+	if _, disallowed := disallowedFields[PropertiesColumn]; disallowed {
+		return n, errors.BadRequest("%s is not a valid field name", name)
+	}
+	if schema != nil && !schema.allowsProperties(column) {
+		return n, schema.unknownField(name)
+	}
+
+	return tsl.Node{
+		Func: n.Func,
+		Left: tsl.Node{
+			Func: tsl.IdentOp,
+			Left: propertyExpression(column, path, propertyType(n)),
+		},
+		Right: n.Right,
+	}, nil
+}
+
+// getField gets the sql field associated with a name, the column of the field of the schema when there is one.
+func getField(name string, disallowedFields map[string]string, schema *SearchSchema) (field string, err *errors.ServiceError) {
 	// We want to accept names with trailing and leading spaces
 	trimmedName := strings.Trim(name, " ")
 
-	// Check for properties ->> '<some field name>'
-	if strings.HasPrefix(trimmedName, "properties ->>") {
-		field = trimmedName
+	// Check for properties.<some field name>, compared as text
+	column, path, err := propertyPath(trimmedName)
+	if err != nil {
+		return
+	}
+	if column != "" {
+		if _, disallowed := disallowedFields[PropertiesColumn]; disallowed {
+			err = errors.BadRequest("%s is not a valid field name", name)
+			return
+		}
+		if schema != nil && !schema.allowsProperties(column) {
+			err = schema.unknownField(trimmedName)
+			return
+		}
+		field = propertyExpression(column, path, "text")
 		return
 	}
 
@@ -60,58 +206,38 @@ func getField(name string, disallowedFields map[string]string) (field string, er
 		err = errors.BadRequest("%s is not a valid field name", name)
 		return
 	}
+	if schema != nil {
+		return schema.Column(trimmedName)
+	}
 	field = trimmedName
 	return
 }
 
-// propertiesNodeConverter converts a node with a properties identifier
-// to a properties node.
-//
-// For example, it will convert:
-// ( properties.<name> = <value> ) to
-// ( properties ->> <name> = <value> )
-func propertiesNodeConverter(n tsl.Node) tsl.Node {
-
-	// Get the left side operator.
-	l, ok := n.Left.(tsl.Node)
-	if !ok {
-		return n
-	}
-
-	// Get the property name.
-	propetyName := l.Left.(string)[11:]
-
-	// Build a new node that converts:
-	// ( properties.<name> = <value> ) to
-	// ( properties ->> <name> = <value> )
-	propertyNode := tsl.Node{
-		Func: n.Func,
-		Left: tsl.Node{
-			Func: tsl.IdentOp,
-			Left: fmt.Sprintf("properties ->> '%s'", propetyName),
-		},
-		Right: n.Right,
-	}
-
-	return propertyNode
-}
-
 // FieldNameWalk walks on the filter tree and check/replace
 // the search fields names:
 // a. the the field name is valid.
 // b. replace the field name with the SQL column name.
+// When the kind has a search schema, the fields must be in it and are compared with its operators
+// and values of their type, the schema being nil otherwise.
 func FieldNameWalk(
 	n tsl.Node,
-	disallowedFields map[string]string) (newNode tsl.Node, err *errors.ServiceError) {
+	disallowedFields map[string]string,
+	schema *SearchSchema) (newNode tsl.Node, err *errors.ServiceError) {
 
 	var field string
 	var l, r tsl.Node
 
 	// Check for properties.<name> = <value> nodes, and convert them to
-	// ( properties ->> <name> = <value> )
-	// nodes.
+	// nodes comparing the property with the type of the value.
 	if hasProperty(n) {
-		n = propertiesNodeConverter(n)
+		return propertiesNodeConverter(n, disallowedFields, schema)
+	}
+
+	// Check the comparisons of the fields with their types.
+	if schema != nil && isComparison(n) {
+		if err = schema.checkComparison(n); err != nil {
+			return
+		}
 	}
 
 	switch n.Func {
@@ -124,7 +250,7 @@ func FieldNameWalk(
 		}
 
 		// Check field name in the disallowedFields field names.
-		field, err = getField(userFieldName, disallowedFields)
+		field, err = getField(userFieldName, disallowedFields, schema)
 		if err != nil {
 			return
 		}
@@ -137,7 +263,7 @@ func FieldNameWalk(
 	default:
 		// o/w continue walking the tree.
 		if n.Left != nil {
-			l, err = FieldNameWalk(n.Left.(tsl.Node), disallowedFields)
+			l, err = FieldNameWalk(n.Left.(tsl.Node), disallowedFields, schema)
 			if err != nil {
 				return
 			}
@@ -148,7 +274,7 @@ func FieldNameWalk(
 			switch v := n.Right.(type) {
 			case tsl.Node:
 				// It's a regular node, just add it.
-				r, err = FieldNameWalk(v, disallowedFields)
+				r, err = FieldNameWalk(v, disallowedFields, schema)
 				if err != nil {
 					return
 				}
@@ -162,7 +288,7 @@ func FieldNameWalk(
 
 				// Add all nodes in the right side array.
 				for _, e := range v {
-					r, err = FieldNameWalk(e, disallowedFields)
+					r, err = FieldNameWalk(e, disallowedFields, schema)
 					if err != nil {
 						return
 					}
@@ -189,7 +315,7 @@ func FieldNameWalk(
 }
 
 // cleanOrderBy takes the orderBy arg and cleans it.
-func cleanOrderBy(userArg string, disallowedFields map[string]string) (orderBy string, err *errors.ServiceError) {
+func cleanOrderBy(userArg string, disallowedFields map[string]string, schema *SearchSchema) (orderBy string, err *errors.ServiceError) {
 	var orderField string
 
 	// We want to accept user params with trailing and leading spaces
@@ -200,12 +326,16 @@ func cleanOrderBy(userArg string, disallowedFields map[string]string) (orderBy s
 	direction := "none valid"
 
 	if len(order) == 1 {
-		orderField, err = getField(order[0], disallowedFields)
+		orderField, err = getField(order[0], disallowedFields, schema)
 		direction = "asc"
 	} else if len(order) == 2 {
-		orderField, err = getField(order[0], disallowedFields)
+		orderField, err = getField(order[0], disallowedFields, schema)
 		direction = order[1]
 	}
+	// the fields of the schemas have helpful errors of their own
+	if err != nil && schema != nil {
+		return
+	}
 	if err != nil || (direction != "asc" && direction != "desc") {
 		err = errors.BadRequest("bad order value '%s'", userArg)
 		return
@@ -215,16 +345,17 @@ func cleanOrderBy(userArg string, disallowedFields map[string]string) (orderBy s
 	return
 }
 
-// ArgsToOrderBy returns cleaned orderBy list.
+// ArgsToOrderBy returns cleaned orderBy list, of the fields of the search schema of the kind when it has one.
 func ArgsToOrderBy(
 	orderByArgs []string,
-	disallowedFields map[string]string) (orderBy []string, err *errors.ServiceError) {
+	disallowedFields map[string]string,
+	schema *SearchSchema) (orderBy []string, err *errors.ServiceError) {
 
 	var order string
 	if len(orderByArgs) != 0 {
 		orderBy = []string{}
 		for _, o := range orderByArgs {
-			order, err = cleanOrderBy(o, disallowedFields)
+			order, err = cleanOrderBy(o, disallowedFields, schema)
 			if err != nil {
 				return
 			}
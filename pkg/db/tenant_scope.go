@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/openshift-online/rh-trex/pkg/auth"
+)
+
+// tenantFieldName is the field holding the organization owning a record, see api.Meta
+const tenantFieldName = "OrgID"
+
+// RegisterTenantScope registers the GORM callbacks confining the statements on the models with an OrgID field
+// to the tenant of the statement context, see auth.SetTenantContext: created records are stamped with the tenant,
+// queries, updates and deletes only reach the records of the tenant.
+// Administrators, and the contexts without a tenant such as the controllers' and the jobs', are not confined.
+func RegisterTenantScope(g2 *gorm.DB) error {
+	if err := g2.Callback().Create().Before("gorm:create").Register("trex:tenant_create", stampTenant); err != nil {
+		return err
+	}
+	if err := g2.Callback().Query().Before("gorm:query").Register("trex:tenant_query", scopeToTenant); err != nil {
+		return err
+	}
+	if err := g2.Callback().Row().Before("gorm:row").Register("trex:tenant_row", scopeToTenant); err != nil {
+		return err
+	}
+	if err := g2.Callback().Update().Before("gorm:update").Register("trex:tenant_update", scopeWritesToTenant); err != nil {
+		return err
+	}
+	return g2.Callback().Delete().Before("gorm:delete").Register("trex:tenant_delete", scopeWritesToTenant)
+}
+
+func tenantField(stmt *gorm.Statement) *schema.Field {
+	if stmt.Schema == nil {
+		return nil
+	}
+	return stmt.Schema.LookUpField(tenantFieldName)
+}
+
+// TenantColumn returns the column holding the organization owning the records of the schema,
+// ok is false for the schemas whose records belong to none
+func TenantColumn(s *schema.Schema) (column string, ok bool) {
+	field := s.LookUpField(tenantFieldName)
+	if field == nil {
+		return "", false
+	}
+	return field.DBName, true
+}
+
+// stampTenant sets the tenant of the records created without one
+func stampTenant(g2 *gorm.DB) {
+	orgID, ok := auth.GetTenantFromContext(g2.Statement.Context)
+	field := tenantField(g2.Statement)
+	if !ok || field == nil {
+		return
+	}
+
+	stamp := func(record reflect.Value) {
+		if _, zero := field.ValueOf(record); zero {
+			if err := field.Set(record, orgID); err != nil {
+				_ = g2.AddError(err)
+			}
+		}
+	}
+	switch records := g2.Statement.ReflectValue; records.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < records.Len(); i++ {
+			stamp(reflect.Indirect(records.Index(i)))
+		}
+	case reflect.Struct:
+		stamp(records)
+	}
+}
+
+// ConfiningTenant returns the tenant the statements of the context are confined to, if any
+func ConfiningTenant(ctx context.Context) (string, bool) {
+	orgID, ok := auth.GetTenantFromContext(ctx)
+	if !ok || auth.IsAdmin(ctx) {
+		return "", false
+	}
+	return orgID, true
+}
+
+// scopeToTenant adds the tenant to the conditions of the statement
+func scopeToTenant(g2 *gorm.DB) {
+	orgID, confined := ConfiningTenant(g2.Statement.Context)
+	field := tenantField(g2.Statement)
+	if !confined || field == nil {
+		return
+	}
+
+	g2.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: g2.Statement.Table, Name: field.DBName}, Value: orgID},
+	}})
+}
+
+// scopeWritesToTenant adds the tenant to the conditions of the updates and deletes,
+// leaving gorm to reject the ones without conditions: the tenant isn't one.
+func scopeWritesToTenant(g2 *gorm.DB) {
+	if !hasConditions(g2.Statement) && !g2.AllowGlobalUpdate {
+		return
+	}
+	scopeToTenant(g2)
+}
+
+// hasConditions returns false for the statements that would reach every record of the table,
+// gorm adds the primary keys of the records as conditions of the updates and deletes.
+func hasConditions(stmt *gorm.Statement) bool {
+	if _, ok := stmt.Clauses["WHERE"]; ok {
+		return true
+	}
+	if stmt.Schema == nil || stmt.ReflectValue.Kind() != reflect.Struct {
+		return true
+	}
+	for _, field := range stmt.Schema.PrimaryFields {
+		if _, zero := field.ValueOf(stmt.ReflectValue); !zero {
+			return true
+		}
+	}
+	return false
+}
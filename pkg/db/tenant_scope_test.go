@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/openshift-online/rh-trex/pkg/auth"
+)
+
+type tenantRecord struct {
+	ID      string
+	OrgID   string
+	Species string
+}
+
+func TestTenantScope(t *testing.T) {
+	RegisterTestingT(t)
+
+	// dry runs don't need a database
+	g2, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(RegisterTenantScope(g2)).To(Succeed())
+
+	tenantCtx := auth.SetTenantContext(context.Background(), "org-a")
+
+	record := &tenantRecord{ID: "1", Species: "rex"}
+	g2.WithContext(tenantCtx).Create(record)
+	Expect(record.OrgID).To(Equal("org-a"))
+
+	// the records created for another organization keep it
+	record = &tenantRecord{ID: "2", OrgID: "org-b"}
+	g2.WithContext(tenantCtx).Create(record)
+	Expect(record.OrgID).To(Equal("org-b"))
+
+	stmt := g2.WithContext(tenantCtx).Where("species = ?", "rex").Find(&[]tenantRecord{}).Statement
+	Expect(stmt.SQL.String()).To(ContainSubstring(`"tenant_records"."org_id" = $2`))
+	Expect(stmt.Vars).To(ContainElement("org-a"))
+
+	stmt = g2.WithContext(tenantCtx).Model(&tenantRecord{ID: "1"}).Update("species", "t-rex").Statement
+	Expect(stmt.SQL.String()).To(ContainSubstring(`"tenant_records"."org_id" =`))
+
+	// gorm still rejects the updates reaching every record
+	Expect(g2.WithContext(tenantCtx).Model(&tenantRecord{}).Update("species", "t-rex").Error).To(MatchError(gorm.ErrMissingWhereClause))
+
+	// neither administrators nor the contexts without a tenant are confined
+	stmt = g2.WithContext(auth.SetAdminContext(tenantCtx)).Find(&[]tenantRecord{}).Statement
+	Expect(stmt.SQL.String()).NotTo(ContainSubstring("org_id"))
+	stmt = g2.WithContext(context.Background()).Find(&[]tenantRecord{}).Statement
+	Expect(stmt.SQL.String()).NotTo(ContainSubstring("org_id"))
+}
@@ -3,6 +3,7 @@ package transaction
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 )
 
 // By default do no roll back transaction.
@@ -12,8 +13,21 @@ const defaultRollbackPolicy = false
 // Transaction represents an sql transaction
 type Transaction struct {
 	rollbackFlag bool
+	rollbackErr  error
 	tx           *sql.Tx
 	txid         int64
+	savepoints   []savepoint
+	savepointSeq int
+	afterResolve []func()
+	committed    bool
+}
+
+// savepoint remembers the rollback flag at the time the savepoint was established,
+// rolling back to the savepoint also undoes MarkForRollback calls made after it.
+type savepoint struct {
+	name         string
+	rollbackFlag bool
+	rollbackErr  error
 }
 
 // Build Creates a new transaction object
@@ -25,6 +39,22 @@ func Build(tx *sql.Tx, id int64, rollbackFlag bool) *Transaction {
 	}
 }
 
+// Restart continues the resolved transaction with a new sql transaction, clearing its rollback flag,
+// rollback error and savepoints, numbered from the start again, so that the work done in it can be retried from scratch.
+func (tx *Transaction) Restart(sqlTx *sql.Tx, id int64) error {
+	if tx.tx != nil {
+		return errors.New("db: transaction hasn't been resolved yet")
+	}
+	tx.tx = sqlTx
+	tx.txid = id
+	tx.rollbackFlag = defaultRollbackPolicy
+	tx.rollbackErr = nil
+	tx.savepoints = nil
+	tx.savepointSeq = 0
+	tx.committed = false
+	return nil
+}
+
 // MarkedForRollback returns true if a transaction is flagged for rollback and false otherwise.
 func (tx *Transaction) MarkedForRollback() bool {
 	return tx.rollbackFlag
@@ -48,6 +78,8 @@ func (tx *Transaction) Commit() error {
 	// do *not* call commit on the underlying transaction itself. Gorm does that.
 	err := tx.tx.Commit()
 	tx.tx = nil
+	tx.committed = err == nil
+	tx.resolved()
 	return err
 }
 
@@ -59,9 +91,106 @@ func (tx *Transaction) Rollback() error {
 	}
 	err := tx.tx.Rollback()
 	tx.tx = nil
+	tx.committed = false
+	tx.resolved()
 	return err
 }
 
+// Committed returns true once the transaction has been committed successfully, and false while it is active,
+// after it was rolled back or when its commit failed.
+func (tx *Transaction) Committed() bool {
+	return tx.committed
+}
+
+// AfterResolve registers fn to run once the transaction is committed or rolled back,
+// e.g. to release resources that must outlive the writes made in the transaction.
+// fn runs right away when the transaction has already ended.
+func (tx *Transaction) AfterResolve(fn func()) {
+	if tx.tx == nil {
+		fn()
+		return
+	}
+	tx.afterResolve = append(tx.afterResolve, fn)
+}
+
+// resolved runs the AfterResolve functions in reverse order of registration, like deferred calls.
+func (tx *Transaction) resolved() {
+	fns := tx.afterResolve
+	tx.afterResolve = nil
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i]()
+	}
+}
+
 func (tx *Transaction) SetRollbackFlag(flag bool) {
 	tx.rollbackFlag = flag
 }
+
+// SetRollbackError records the error that caused the transaction to be marked for rollback.
+func (tx *Transaction) SetRollbackError(err error) {
+	tx.rollbackErr = err
+}
+
+// RollbackError returns the first error recorded by SetRollbackError, if any.
+func (tx *Transaction) RollbackError() error {
+	return tx.rollbackErr
+}
+
+// Savepoint establishes a new savepoint in the transaction and returns its name.
+func (tx *Transaction) Savepoint() (string, error) {
+	if tx.tx == nil {
+		return "", errors.New("db: transaction hasn't been started yet")
+	}
+	tx.savepointSeq++
+	name := fmt.Sprintf("trex_savepoint_%d", tx.savepointSeq)
+	if _, err := tx.tx.Exec("SAVEPOINT " + name); err != nil {
+		return "", err
+	}
+	tx.savepoints = append(tx.savepoints, savepoint{name: name, rollbackFlag: tx.rollbackFlag, rollbackErr: tx.rollbackErr})
+	return name, nil
+}
+
+// RollbackTo undoes everything done in the transaction after the named savepoint was established,
+// including the savepoints established after it. The named savepoint itself remains established.
+func (tx *Transaction) RollbackTo(name string) error {
+	if tx.tx == nil {
+		return errors.New("db: transaction hasn't been started yet")
+	}
+	i, err := tx.savepointIndex(name)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.tx.Exec("ROLLBACK TO SAVEPOINT " + name); err != nil {
+		return err
+	}
+	tx.rollbackFlag = tx.savepoints[i].rollbackFlag
+	tx.rollbackErr = tx.savepoints[i].rollbackErr
+	tx.savepoints = tx.savepoints[:i+1]
+	return nil
+}
+
+// Release destroys the named savepoint and the savepoints established after it,
+// keeping the changes made since it was established.
+func (tx *Transaction) Release(name string) error {
+	if tx.tx == nil {
+		return errors.New("db: transaction hasn't been started yet")
+	}
+	i, err := tx.savepointIndex(name)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.tx.Exec("RELEASE SAVEPOINT " + name); err != nil {
+		return err
+	}
+	tx.savepoints = tx.savepoints[:i]
+	return nil
+}
+
+func (tx *Transaction) savepointIndex(name string) (int, error) {
+	for i := len(tx.savepoints) - 1; i >= 0; i-- {
+		if tx.savepoints[i].name == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("db: savepoint %s does not exist", name)
+}
@@ -2,7 +2,10 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 
+	dbContext "github.com/openshift-online/rh-trex/pkg/db/db_context"
 	"github.com/openshift-online/rh-trex/pkg/db/transaction"
 )
 
@@ -17,10 +20,38 @@ func newTransaction(ctx context.Context, connection SessionFactory) (*transactio
 		return nil, nil
 	}
 
+	tx, txid, err := begin(connection)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction.Build(tx, txid, defaultRollbackPolicy), nil
+}
+
+// restartTransaction begins a new transaction in place of the resolved transaction stored in the context,
+// see transaction.Transaction.Restart
+func restartTransaction(ctx context.Context, connection SessionFactory) error {
+	current, ok := dbContext.Transaction(ctx)
+	if !ok || current == nil {
+		return errors.New("could not retrieve transaction from context")
+	}
+	tx, txid, err := begin(connection)
+	if err != nil {
+		return err
+	}
+	if err := current.Restart(tx, txid); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// begin begins a new sql transaction and returns it along with its postgres transaction ID.
+func begin(connection SessionFactory) (*sql.Tx, int64, error) {
 	dbx := connection.DirectDB()
 	tx, err := dbx.Begin()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// current transaction ID set by postgres.  these are *not* distinct across time
@@ -30,9 +61,9 @@ func newTransaction(ctx context.Context, connection SessionFactory) (*transactio
 	if row != nil {
 		err := row.Scan(&txid)
 		if err != nil {
-			return nil, err
+			_ = tx.Rollback()
+			return nil, 0, err
 		}
 	}
-
-	return transaction.Build(tx, txid, defaultRollbackPolicy), nil
+	return tx, txid, nil
 }
@@ -58,8 +58,17 @@ const (
 
 	// DatabaseAdvisoryLock occurs whe the advisory lock is failed to get
 	ErrorDatabaseAdvisoryLock ServiceErrorCode = 26
+
+	// DatabaseAdvisoryLockTimeout occurs when the advisory lock is held by someone else for longer than we can wait
+	ErrorDatabaseAdvisoryLockTimeout ServiceErrorCode = 27
+
+	// DatabaseTransient occurs when a transaction failed on a serialization failure or a deadlock and may succeed when retried
+	ErrorDatabaseTransient ServiceErrorCode = 28
 )
 
+// RetryAfterSeconds is sent in the Retry-After header of responses for retryable errors
+var RetryAfterSeconds = 1
+
 type ServiceErrorCode int
 
 type ServiceErrors []ServiceError
@@ -88,6 +97,8 @@ func Errors() ServiceErrors {
 		ServiceError{ErrorBadRequest, "Bad request", http.StatusBadRequest},
 		ServiceError{ErrorFailedToParseSearch, "Failed to parse search query", http.StatusBadRequest},
 		ServiceError{ErrorDatabaseAdvisoryLock, "Database advisory lock error", http.StatusInternalServerError},
+		ServiceError{ErrorDatabaseAdvisoryLockTimeout, "Timed out waiting for the database advisory lock", http.StatusServiceUnavailable},
+		ServiceError{ErrorDatabaseTransient, "Conflict with concurrent database transactions, retry the request", http.StatusServiceUnavailable},
 	}
 }
 
@@ -138,6 +149,12 @@ func (e *ServiceError) IsForbidden() bool {
 	return e.Code == Forbidden("").Code
 }
 
+// IsRetryable returns true if the same request may succeed when retried later,
+// these errors are sent with a Retry-After header.
+func (e *ServiceError) IsRetryable() bool {
+	return e.HttpCode == http.StatusServiceUnavailable
+}
+
 func (e *ServiceError) AsOpenapiError(operationID string) openapi.Error {
 	return openapi.Error{
 		Kind:        openapi.PtrString("Error"),
@@ -205,3 +222,11 @@ func FailedToParseSearch(reason string, values ...interface{}) *ServiceError {
 func DatabaseAdvisoryLock(err error) *ServiceError {
 	return New(ErrorDatabaseAdvisoryLock, err.Error(), []string{})
 }
+
+func DatabaseAdvisoryLockTimeout(reason string, values ...interface{}) *ServiceError {
+	return New(ErrorDatabaseAdvisoryLockTimeout, reason, values...)
+}
+
+func DatabaseTransient(reason string, values ...interface{}) *ServiceError {
+	return New(ErrorDatabaseTransient, reason, values...)
+}
@@ -23,3 +23,11 @@ func TestErrorFind(t *testing.T) {
 	Expect(exists).To(Equal(false))
 	Expect(err).To(BeNil())
 }
+
+func TestErrorIsRetryable(t *testing.T) {
+	RegisterTestingT(t)
+	Expect(DatabaseAdvisoryLockTimeout("lock held").IsRetryable()).To(BeTrue())
+	Expect(DatabaseAdvisoryLockTimeout("lock held").HttpCode).To(Equal(503))
+	Expect(GeneralError("boom").IsRetryable()).To(BeFalse())
+	Expect(Conflict("exists").IsRetryable()).To(BeFalse())
+}
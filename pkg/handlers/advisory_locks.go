@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/openshift-online/rh-trex/pkg/db"
+)
+
+type advisoryLocksHandler struct {
+}
+
+// NewAdvisoryLocksHandler lists the advisory locks held by this process, meant for the internal metrics port.
+func NewAdvisoryLocksHandler() *advisoryLocksHandler {
+	return &advisoryLocksHandler{}
+}
+
+type heldAdvisoryLock struct {
+	Owner    string    `json:"owner"`
+	ID       string    `json:"id"`
+	Type     string    `json:"type"`
+	Acquired time.Time `json:"acquired"`
+	Age      string    `json:"age"`
+}
+
+type heldAdvisoryLockList struct {
+	Kind  string             `json:"kind"`
+	Size  int                `json:"size"`
+	Items []heldAdvisoryLock `json:"items"`
+}
+
+func (h *advisoryLocksHandler) List(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	locks := db.HeldAdvisoryLocks()
+	list := heldAdvisoryLockList{
+		Kind:  "AdvisoryLockList",
+		Size:  len(locks),
+		Items: make([]heldAdvisoryLock, 0, len(locks)),
+	}
+	for _, l := range locks {
+		list.Items = append(list.Items, heldAdvisoryLock{
+			Owner:    l.Owner,
+			ID:       l.ID,
+			Type:     string(l.Type),
+			Acquired: l.Acquired,
+			Age:      now.Sub(l.Acquired).Round(time.Millisecond).String(),
+		})
+	}
+	writeJSONResponse(w, http.StatusOK, list)
+}
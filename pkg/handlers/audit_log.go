@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/api/presenters"
+	"github.com/openshift-online/rh-trex/pkg/errors"
+	"github.com/openshift-online/rh-trex/pkg/services"
+)
+
+type auditLogHandler struct {
+	generic services.GenericService
+}
+
+func NewAuditLogHandler(generic services.GenericService) *auditLogHandler {
+	return &auditLogHandler{
+		generic: generic,
+	}
+}
+
+func (h auditLogHandler) List(w http.ResponseWriter, r *http.Request) {
+	if format := exportFormat(r); format != "" {
+		h.export(w, r, format)
+		return
+	}
+
+	cfg := &handlerConfig{
+		Action: func() (interface{}, *errors.ServiceError) {
+			ctx := r.Context()
+
+			listArgs := services.NewListArguments(r.URL.Query())
+			var auditLogs []api.AuditLog
+			// the saved search the view names is expanded into the arguments
+			if err := h.generic.ExpandView(ctx, listArgs, &auditLogs); err != nil {
+				return nil, err
+			}
+			paging, err := h.generic.List(ctx, "username", listArgs, &auditLogs)
+			if err != nil {
+				return nil, err
+			}
+			auditLogList := presenters.AuditLogList{
+				Kind:     "AuditLogList",
+				Page:     int32(paging.Page),
+				Size:     int32(paging.Size),
+				Total:    int32(paging.Total),
+				Continue: paging.Continue,
+				Items:    []presenters.AuditLog{},
+			}
+
+			for _, auditLog := range auditLogs {
+				auditLogList.Items = append(auditLogList.Items, presenters.PresentAuditLog(&auditLog))
+			}
+			if listArgs.Fields != nil {
+				filteredItems, err := presenters.SliceFilter(listArgs.Fields, auditLogList)
+				if err != nil {
+					return nil, err
+				}
+				return filteredItems, nil
+			}
+			return auditLogList, nil
+		},
+	}
+
+	handleList(w, r, cfg)
+}
+
+// export streams all the audit logs of a list, see handleExport
+func (h auditLogHandler) export(w http.ResponseWriter, r *http.Request, format string) {
+	ctx := r.Context()
+	listArgs := services.NewListArguments(r.URL.Query())
+	var auditLogs []api.AuditLog
+	if err := h.generic.ExpandView(ctx, listArgs, &auditLogs); err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+	handleExport(w, r, format, &exportConfig{
+		Fields: listArgs.Fields,
+		Export: func(each func(resource interface{}) error) *errors.ServiceError {
+			return h.generic.Export(ctx, "username", listArgs, &auditLogs, each)
+		},
+		Present: func(resource interface{}) interface{} {
+			return presenters.PresentAuditLog(resource.(*api.AuditLog))
+		},
+	})
+}
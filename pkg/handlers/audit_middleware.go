@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	dbContext "github.com/openshift-online/rh-trex/pkg/db/db_context"
+	"github.com/openshift-online/rh-trex/pkg/logger"
+	"github.com/openshift-online/rh-trex/pkg/services"
+)
+
+// maxAuditedErrorBody bounds the part of an error response kept to find its reason
+const maxAuditedErrorBody = 4096
+
+type auditMiddleware struct {
+	auditLogs services.AuditLogService
+}
+
+// NewAuditMiddleware records an audit log for every mutating call (POST, PUT, PATCH, DELETE).
+//
+// The middleware must be wrapped by the transaction middleware: the audit log is written outside of the
+// request transaction once it is resolved, with the outcome of its commit, so the calls that fail and
+// roll back are recorded too. The services describe the change they made with services.AuditChange.
+func NewAuditMiddleware(auditLogs services.AuditLogService) *auditMiddleware {
+	return &auditMiddleware{auditLogs: auditLogs}
+}
+
+func (m *auditMiddleware) Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the inner middlewares and the services fill the entry, e.g. the JWT middleware with the caller
+		entryCtx, entry := services.WithAuditEntry(r.Context())
+		recorder := &auditResponseWriter{wrapped: w}
+		next.ServeHTTP(recorder, r.WithContext(entryCtx))
+
+		tx, ok := dbContext.Transaction(r.Context())
+		if !ok || tx == nil {
+			m.record(r, entry, recorder, true)
+			return
+		}
+		// runs right away when the transaction is already resolved, e.g. committed by db.RetryTransaction
+		tx.AfterResolve(func() {
+			m.record(r, entry, recorder, tx.Committed())
+		})
+	})
+}
+
+// record saves the audit log of the call, committed tells whether its changes were committed.
+func (m *auditMiddleware) record(r *http.Request, entry *services.AuditEntry, recorder *auditResponseWriter, committed bool) {
+	change := entry.Snapshot()
+	// the context without the request transaction, to write the audit log with
+	ctx := dbContext.WithoutTransaction(r.Context())
+	auditLog := &api.AuditLog{
+		Actor:        change.Actor,
+		OperationID:  logger.GetOperationID(ctx),
+		Method:       r.Method,
+		Path:         r.URL.Path,
+		ResourceKind: change.ResourceKind,
+		ResourceID:   change.ResourceID,
+		Before:       change.Before,
+		After:        change.After,
+		Changes:      change.Changes,
+		StatusCode:   recorder.statusCode(),
+		Outcome:      api.AuditSuccess,
+	}
+	// the audit logs belong to the organization of the caller
+	auditLog.OrgID = change.OrgID
+	if auditLog.ResourceID == "" {
+		auditLog.ResourceID = mux.Vars(r)["id"]
+	}
+	switch {
+	case auditLog.StatusCode >= http.StatusBadRequest:
+		auditLog.Outcome = api.AuditFailure
+		auditLog.Reason = recorder.reason()
+	case !committed:
+		// the response was sent before the transaction failed to commit
+		auditLog.Outcome = api.AuditFailure
+		auditLog.Reason = "The changes of the call were not committed"
+	}
+
+	// the response is already sent, a failure to record it can only be logged
+	if _, err := m.auditLogs.Record(ctx, auditLog); err != nil {
+		logger.NewOCMLogger(ctx).Error(fmt.Sprintf("Unable to record the audit log of %s %s: %s", r.Method, r.URL.Path, err))
+	}
+}
+
+// auditResponseWriter remembers the status code of the response, and the beginning of its body for errors.
+type auditResponseWriter struct {
+	wrapped http.ResponseWriter
+	code    int
+	body    bytes.Buffer
+}
+
+func (w *auditResponseWriter) Header() http.Header {
+	return w.wrapped.Header()
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if w.code >= http.StatusBadRequest && w.body.Len() < maxAuditedErrorBody {
+		remaining := maxAuditedErrorBody - w.body.Len()
+		if len(b) < remaining {
+			remaining = len(b)
+		}
+		w.body.Write(b[:remaining])
+	}
+	return w.wrapped.Write(b)
+}
+
+func (w *auditResponseWriter) WriteHeader(code int) {
+	w.code = code
+	w.wrapped.WriteHeader(code)
+}
+
+func (w *auditResponseWriter) statusCode() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// reason returns the reason of an error response, or its raw body when it isn't an API error.
+func (w *auditResponseWriter) reason() string {
+	var apiErr struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(w.body.Bytes(), &apiErr); err == nil && apiErr.Reason != "" {
+		return apiErr.Reason
+	}
+	return w.body.String()
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/openshift-online/rh-trex/pkg/api/presenters"
 	"github.com/openshift-online/rh-trex/pkg/errors"
 	"github.com/openshift-online/rh-trex/pkg/services"
+	"github.com/openshift-online/rh-trex/pkg/util"
 )
 
 var _ RestHandler = dinosaurHandler{}
@@ -33,6 +34,7 @@ func (h dinosaurHandler) Create(w http.ResponseWriter, r *http.Request) {
 		[]validate{
 			validateEmpty(&dinosaur, "Id", "id"),
 			validateNotEmpty(&dinosaur, "Species", "species"),
+			validateLabels(&dinosaur, "Labels"),
 		},
 		func() (interface{}, *errors.ServiceError) {
 			ctx := r.Context()
@@ -56,13 +58,15 @@ func (h dinosaurHandler) Patch(w http.ResponseWriter, r *http.Request) {
 		&patch,
 		[]validate{
 			validateDinosaurPatch(&patch),
+			validateLabels(&patch, "Labels"),
 		},
 		func() (interface{}, *errors.ServiceError) {
 			ctx := r.Context()
 			id := mux.Vars(r)["id"]
+			// the species and labels left out of the patch are kept
 			dino, err := h.dinosaur.Replace(ctx, &api.Dinosaur{
-				Meta:    api.Meta{ID: id},
-				Species: *patch.Species,
+				Meta:    api.Meta{ID: id, Labels: presenters.ConvertLabels(patch.Labels)},
+				Species: util.NilToEmptyString(patch.Species),
 			})
 			if err != nil {
 				return nil, err
@@ -76,22 +80,32 @@ func (h dinosaurHandler) Patch(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h dinosaurHandler) List(w http.ResponseWriter, r *http.Request) {
+	if format := exportFormat(r); format != "" {
+		h.export(w, r, format)
+		return
+	}
+
 	cfg := &handlerConfig{
 		Action: func() (interface{}, *errors.ServiceError) {
 			ctx := r.Context()
 
 			listArgs := services.NewListArguments(r.URL.Query())
 			var dinosaurs []api.Dinosaur
+			// the saved search the view names is expanded into the arguments
+			if err := h.generic.ExpandView(ctx, listArgs, &dinosaurs); err != nil {
+				return nil, err
+			}
 			paging, err := h.generic.List(ctx, "username", listArgs, &dinosaurs)
 			if err != nil {
 				return nil, err
 			}
 			dinoList := openapi.DinosaurList{
-				Kind:  "DinosaurList",
-				Page:  int32(paging.Page),
-				Size:  int32(paging.Size),
-				Total: int32(paging.Total),
-				Items: []openapi.Dinosaur{},
+				Kind:     "DinosaurList",
+				Page:     int32(paging.Page),
+				Size:     int32(paging.Size),
+				Total:    int32(paging.Total),
+				Continue: util.EmptyStringToNil(paging.Continue),
+				Items:    []openapi.Dinosaur{},
 			}
 
 			for _, dino := range dinosaurs {
@@ -99,7 +113,7 @@ func (h dinosaurHandler) List(w http.ResponseWriter, r *http.Request) {
 				dinoList.Items = append(dinoList.Items, converted)
 			}
 			if listArgs.Fields != nil {
-				filteredItems, err := presenters.SliceFilter(listArgs.Fields, dinoList.Items)
+				filteredItems, err := presenters.SliceFilter(listArgs.Fields, dinoList)
 				if err != nil {
 					return nil, err
 				}
@@ -112,15 +126,68 @@ func (h dinosaurHandler) List(w http.ResponseWriter, r *http.Request) {
 	handleList(w, r, cfg)
 }
 
+// Aggregate groups the dinosaurs and computes the aggregates of each group
+func (h dinosaurHandler) Aggregate(w http.ResponseWriter, r *http.Request) {
+	cfg := &handlerConfig{
+		Action: func() (interface{}, *errors.ServiceError) {
+			ctx := r.Context()
+
+			aggregateArgs := services.NewAggregateArguments(r.URL.Query())
+			var dinosaurs []api.Dinosaur
+			aggregations, err := h.generic.Aggregate(ctx, "username", aggregateArgs, &dinosaurs)
+			if err != nil {
+				return nil, err
+			}
+			return presenters.PresentAggregationList("Dinosaur", aggregations), nil
+		},
+	}
+
+	handleList(w, r, cfg)
+}
+
+// export streams all the dinosaurs of a list, see handleExport
+func (h dinosaurHandler) export(w http.ResponseWriter, r *http.Request, format string) {
+	ctx := r.Context()
+	listArgs := services.NewListArguments(r.URL.Query())
+	var dinosaurs []api.Dinosaur
+	if err := h.generic.ExpandView(ctx, listArgs, &dinosaurs); err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+	handleExport(w, r, format, &exportConfig{
+		Fields: listArgs.Fields,
+		Export: func(each func(resource interface{}) error) *errors.ServiceError {
+			return h.generic.Export(ctx, "username", listArgs, &dinosaurs, each)
+		},
+		Present: func(resource interface{}) interface{} {
+			return presenters.PresentDinosaur(resource.(*api.Dinosaur))
+		},
+	})
+}
+
 func (h dinosaurHandler) Get(w http.ResponseWriter, r *http.Request) {
 	cfg := &handlerConfig{
 		Action: func() (interface{}, *errors.ServiceError) {
 			id := mux.Vars(r)["id"]
 			ctx := r.Context()
-			dinosaur, err := h.dinosaur.Get(ctx, id)
+			asOf, err := parseAsOf(r)
+			if err != nil {
+				return nil, err
+			}
+
+			var dinosaur *api.Dinosaur
+			if asOf != nil {
+				dinosaur, err = h.dinosaur.GetAsOf(ctx, id, *asOf)
+			} else {
+				dinosaur, err = h.dinosaur.Get(ctx, id)
+			}
 			if err != nil {
 				return nil, err
 			}
+			// the related resources asked for are presented nested in it
+			if err := h.generic.Include(ctx, services.NewIncludeArguments(r.URL.Query()), dinosaur); err != nil {
+				return nil, err
+			}
 
 			return presenters.PresentDinosaur(dinosaur), nil
 		},
@@ -143,3 +210,66 @@ func (h dinosaurHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 	handleDelete(w, r, cfg, http.StatusNoContent)
 }
+
+// Restore undeletes a soft-deleted dinosaur
+func (h dinosaurHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	cfg := &handlerConfig{
+		Action: func() (interface{}, *errors.ServiceError) {
+			id := mux.Vars(r)["id"]
+			ctx := r.Context()
+			dinosaur, err := h.dinosaur.Restore(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return presenters.PresentDinosaur(dinosaur), nil
+		},
+	}
+	// no request body, the restored dinosaur is returned as a get does
+	handleGet(w, r, cfg)
+}
+
+// Purge permanently deletes a dinosaur, soft-deleted or not
+func (h dinosaurHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	cfg := &handlerConfig{
+		Action: func() (interface{}, *errors.ServiceError) {
+			id := mux.Vars(r)["id"]
+			ctx := r.Context()
+			err := h.dinosaur.Purge(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return nil, nil
+		},
+	}
+	handleDelete(w, r, cfg, http.StatusNoContent)
+}
+
+// History lists the versions of a dinosaur, oldest first
+func (h dinosaurHandler) History(w http.ResponseWriter, r *http.Request) {
+	cfg := &handlerConfig{
+		Action: func() (interface{}, *errors.ServiceError) {
+			id := mux.Vars(r)["id"]
+			ctx := r.Context()
+			versions, err := h.dinosaur.History(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+
+			versionList := presenters.ResourceVersionList{
+				Kind:  "DinosaurVersionList",
+				Size:  int32(len(versions)),
+				Items: []presenters.ResourceVersion{},
+			}
+			for _, version := range versions {
+				var dinosaur api.Dinosaur
+				if err := version.Decode(&dinosaur); err != nil {
+					return nil, errors.GeneralError("Unable to decode version %s of dinosaur %s: %s", version.ID, id, err)
+				}
+				versionList.Items = append(versionList.Items, presenters.PresentResourceVersion("Dinosaur", version, presenters.PresentDinosaur(&dinosaur)))
+			}
+			return versionList, nil
+		},
+	}
+
+	handleList(w, r, cfg)
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/openshift-online/rh-trex/pkg/api/presenters"
+	"github.com/openshift-online/rh-trex/pkg/errors"
+	"github.com/openshift-online/rh-trex/pkg/logger"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+	csvContentType    = "text/csv"
+)
+
+// exportConfig streams the resources of a list instead of returning a page of them.
+//
+//	Fields is the fields of the resources to export, all of them when empty.
+//	Export runs the list, passing each resource to the given func as it is loaded.
+//	Present is the API representation of a resource of the list.
+type exportConfig struct {
+	Fields  []string
+	Export  func(each func(resource interface{}) error) *errors.ServiceError
+	Present func(resource interface{}) interface{}
+}
+
+// exportFormat returns the export format a list request accepts, empty for a page of JSON
+func exportFormat(r *http.Request) string {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.Split(accept, ";")[0])
+		if mediaType == ndjsonContentType || mediaType == csvContentType {
+			return mediaType
+		}
+	}
+	return ""
+}
+
+// handleExport writes the resources of a list as they are loaded, one JSON object per line for application/x-ndjson
+// and one record per resource after a header of the fields for text/csv, keeping the memory constant whatever the
+// number of resources. The errors before the first resource are returned as usual. The ones after it can't change
+// the status anymore, they abort the response for the client not to take the truncated export for a complete one.
+func handleExport(w http.ResponseWriter, r *http.Request, format string, cfg *exportConfig) {
+	buffered := bufio.NewWriter(w)
+	var writer exportWriter = &ndjsonExportWriter{encoder: json.NewEncoder(buffered)}
+	if format == csvContentType {
+		writer = &csvExportWriter{writer: csv.NewWriter(buffered), columns: cfg.Fields}
+	}
+
+	started := false
+	start := func() {
+		w.Header().Set("Content-Type", format)
+		w.Header().Set("Vary", "Authorization")
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	var itemErr *errors.ServiceError
+	serviceErr := cfg.Export(func(resource interface{}) error {
+		var item interface{} = cfg.Present(resource)
+		if len(cfg.Fields) > 0 {
+			filtered, err := presenters.ItemFilter(cfg.Fields, item)
+			if err != nil {
+				itemErr = err
+				return err
+			}
+			item = filtered
+		}
+		if !started {
+			start()
+		}
+		return writer.write(item)
+	})
+	if itemErr != nil {
+		serviceErr = itemErr
+	}
+
+	if serviceErr == nil {
+		if !started {
+			start()
+		}
+		if err := writer.flush(); err != nil {
+			serviceErr = errors.GeneralError("Unable to write the export: %s", err)
+		} else if err := buffered.Flush(); err != nil {
+			serviceErr = errors.GeneralError("Unable to write the export: %s", err)
+		}
+	}
+
+	switch {
+	case serviceErr == nil:
+		return
+	case !started:
+		handleError(r.Context(), w, serviceErr)
+	default:
+		log := logger.NewOCMLogger(r.Context())
+		log.Error("Aborting the export: " + serviceErr.Error())
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportWriter writes the resources of an export in its format
+type exportWriter interface {
+	write(item interface{}) error
+	flush() error
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+// write writes the item as JSON, the encoder ending it with a newline
+func (n *ndjsonExportWriter) write(item interface{}) error {
+	return n.encoder.Encode(item)
+}
+
+func (n *ndjsonExportWriter) flush() error {
+	return nil
+}
+
+// csvExportWriter writes a column per field requested, or per top level field of the resources when none is.
+// The values are the ones of the JSON representation, the nested objects and arrays being written as JSON.
+type csvExportWriter struct {
+	writer  *csv.Writer
+	columns []string
+	header  bool
+}
+
+func (c *csvExportWriter) write(item interface{}) error {
+	if c.columns == nil {
+		c.columns = jsonFieldNames(reflect.TypeOf(item))
+	}
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return err
+	}
+	record := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		if record[i], err = csvValue(lookupField(values, column)); err != nil {
+			return err
+		}
+	}
+	return c.writer.Write(record)
+}
+
+// flush writes the header of the empty exports of requested fields, and whatever is left
+func (c *csvExportWriter) flush() error {
+	if c.columns != nil {
+		if err := c.writeHeader(); err != nil {
+			return err
+		}
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *csvExportWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.writer.Write(c.columns)
+}
+
+// jsonFieldNames returns the names of the JSON fields of a structure, in their order
+func jsonFieldNames(t reflect.Type) []string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	names := []string{}
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// lookupField returns the value of a field of a JSON object, the fields of nested objects being <object>.<field>
+// and <object>.* being the whole object, nil when it is missing
+func lookupField(values map[string]interface{}, field string) interface{} {
+	var value interface{} = values
+	for _, name := range strings.Split(strings.TrimSuffix(field, ".*"), ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+// csvValue formats a JSON value for a CSV record: the strings as they are, nothing for null and JSON otherwise
+func csvValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/api/presenters"
+	"github.com/openshift-online/rh-trex/pkg/errors"
+)
+
+func TestHandleExport(t *testing.T) {
+	RegisterTestingT(t)
+	// the kinds are registered by the plugins
+	presenters.RegisterKind(api.Dinosaur{}, "Dinosaur")
+	presenters.RegisterKind(&api.Dinosaur{}, "Dinosaur")
+	presenters.RegisterPath(api.Dinosaur{}, "dinosaurs")
+	presenters.RegisterPath(&api.Dinosaur{}, "dinosaurs")
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	dinosaurs := []api.Dinosaur{
+		{Meta: api.Meta{ID: "1", CreatedAt: created, UpdatedAt: created, Labels: api.Labels{"tier": "gold"}}, Species: "Stego"},
+		{Meta: api.Meta{ID: "2", CreatedAt: created, UpdatedAt: created}, Species: "Bronto, \"the long\""},
+	}
+	exportConfig := func(fields []string) *exportConfig {
+		return &exportConfig{
+			Fields: fields,
+			Export: func(each func(resource interface{}) error) *errors.ServiceError {
+				for i := range dinosaurs {
+					if err := each(&dinosaurs[i]); err != nil {
+						return errors.GeneralError("%s", err)
+					}
+				}
+				return nil
+			},
+			Present: func(resource interface{}) interface{} {
+				return presenters.PresentDinosaur(resource.(*api.Dinosaur))
+			},
+		}
+	}
+
+	tests := []struct {
+		accept string
+		fields []string
+		body   string
+	}{
+		{
+			accept: "application/x-ndjson",
+			fields: []string{"species", "id"},
+			body:   "{\"id\":\"1\",\"species\":\"Stego\"}\n{\"id\":\"2\",\"species\":\"Bronto, \\\"the long\\\"\"}\n",
+		},
+		{
+			accept: "text/csv",
+			fields: []string{"species", "labels", "id"},
+			body:   "species,labels,id\nStego,\"{\"\"tier\"\":\"\"gold\"\"}\",1\n\"Bronto, \"\"the long\"\"\",{},2\n",
+		},
+		{
+			accept: "text/csv; q=0.9, application/json",
+			body: "id,kind,href,created_at,updated_at,species,labels\n" +
+				"1,Dinosaur,/api/rh-trex/v1/dinosaurs/1,2024-05-01T12:00:00Z,2024-05-01T12:00:00Z,Stego,\"{\"\"tier\"\":\"\"gold\"\"}\"\n" +
+				"2,Dinosaur,/api/rh-trex/v1/dinosaurs/2,2024-05-01T12:00:00Z,2024-05-01T12:00:00Z,\"Bronto, \"\"the long\"\"\",{}\n",
+		},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/dinosaurs", nil)
+		r.Header.Set("Accept", test.accept)
+		format := exportFormat(r)
+		Expect(format).NotTo(BeEmpty())
+
+		w := httptest.NewRecorder()
+		handleExport(w, r, format, exportConfig(test.fields))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(Equal(format))
+		Expect(w.Body.String()).To(Equal(test.body), test.accept)
+	}
+
+	// the invalid fields are rejected before anything is written
+	r := httptest.NewRequest(http.MethodGet, "/dinosaurs", nil)
+	w := httptest.NewRecorder()
+	handleExport(w, r, ndjsonContentType, exportConfig([]string{"teeth"}))
+	Expect(w.Code).To(Equal(http.StatusBadRequest))
+	Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+
+	r.Header.Set("Accept", "application/json")
+	Expect(exportFormat(r)).To(BeEmpty())
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/openshift-online/rh-trex/pkg/errors"
 	"github.com/openshift-online/rh-trex/pkg/logger"
@@ -38,6 +39,9 @@ func handleError(ctx context.Context, w http.ResponseWriter, err *errors.Service
 	} else {
 		log.Error(err.Error())
 	}
+	if err.IsRetryable() {
+		w.Header().Set("Retry-After", strconv.Itoa(errors.RetryAfterSeconds))
+	}
 	writeJSONResponse(w, err.HttpCode, err.AsOpenapiError(operationID))
 }
 
@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"reflect"
+	"strings"
+	"time"
+
+	"github.com/openshift-online/rh-trex/pkg/errors"
 )
 
 func writeJSONResponse(w http.ResponseWriter, code int, payload interface{}) {
@@ -36,3 +40,16 @@ func determineListRange(obj interface{}, page int, size int64) (list []interface
 
 	return list, total
 }
+
+// parseAsOf returns the time of the asOf query parameter (RFC 3339), nil when it isn't set
+func parseAsOf(r *http.Request) (*time.Time, *errors.ServiceError) {
+	v := strings.TrimSpace(r.URL.Query().Get("asOf"))
+	if v == "" {
+		return nil, nil
+	}
+	asOf, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, errors.Validation("asOf must be an RFC 3339 timestamp, e.g. 2006-01-02T15:04:05Z: %s", v)
+	}
+	return &asOf, nil
+}
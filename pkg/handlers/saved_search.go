@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/openshift-online/rh-trex/pkg/api/presenters"
+	"github.com/openshift-online/rh-trex/pkg/errors"
+	"github.com/openshift-online/rh-trex/pkg/services"
+)
+
+var _ RestHandler = savedSearchHandler{}
+
+type savedSearchHandler struct {
+	savedSearch services.SavedSearchService
+}
+
+func NewSavedSearchHandler(savedSearch services.SavedSearchService) *savedSearchHandler {
+	return &savedSearchHandler{
+		savedSearch: savedSearch,
+	}
+}
+
+func (h savedSearchHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var savedSearch presenters.SavedSearch
+	cfg := &handlerConfig{
+		&savedSearch,
+		[]validate{
+			validateEmpty(&savedSearch, "Id", "id"),
+			validateNotEmpty(&savedSearch, "Name", "name"),
+			validateNotEmpty(&savedSearch, "ResourceKind", "resource_kind"),
+		},
+		func() (interface{}, *errors.ServiceError) {
+			ctx := r.Context()
+			saved, err := h.savedSearch.Create(ctx, presenters.ConvertSavedSearch(savedSearch))
+			if err != nil {
+				return nil, err
+			}
+			return presenters.PresentSavedSearch(saved), nil
+		},
+		handleError,
+	}
+
+	handle(w, r, cfg, http.StatusCreated)
+}
+
+func (h savedSearchHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	var patch presenters.SavedSearchPatchRequest
+
+	cfg := &handlerConfig{
+		&patch,
+		[]validate{
+			validateSavedSearchPatch(&patch),
+		},
+		func() (interface{}, *errors.ServiceError) {
+			ctx := r.Context()
+			id := mux.Vars(r)["id"]
+			// the fields left out of the patch are kept
+			saved, err := h.savedSearch.Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			if patch.Name != nil {
+				saved.Name = *patch.Name
+			}
+			if patch.ResourceKind != nil {
+				saved.ResourceKind = *patch.ResourceKind
+			}
+			if patch.Search != nil {
+				saved.Search = *patch.Search
+			}
+			if patch.OrderBy != nil {
+				saved.OrderBy = *patch.OrderBy
+			}
+			if patch.Fields != nil {
+				saved.Fields = *patch.Fields
+			}
+			saved, err = h.savedSearch.Replace(ctx, saved)
+			if err != nil {
+				return nil, err
+			}
+			return presenters.PresentSavedSearch(saved), nil
+		},
+		handleError,
+	}
+
+	handle(w, r, cfg, http.StatusOK)
+}
+
+// List lists the saved searches of the caller, all of them in a single page
+func (h savedSearchHandler) List(w http.ResponseWriter, r *http.Request) {
+	cfg := &handlerConfig{
+		Action: func() (interface{}, *errors.ServiceError) {
+			ctx := r.Context()
+			savedSearches, err := h.savedSearch.All(ctx)
+			if err != nil {
+				return nil, err
+			}
+			savedSearchList := presenters.SavedSearchList{
+				Kind:  "SavedSearchList",
+				Page:  1,
+				Size:  int32(len(savedSearches)),
+				Total: int32(len(savedSearches)),
+				Items: []presenters.SavedSearch{},
+			}
+			for _, savedSearch := range savedSearches {
+				savedSearchList.Items = append(savedSearchList.Items, presenters.PresentSavedSearch(savedSearch))
+			}
+			return savedSearchList, nil
+		},
+	}
+
+	handleList(w, r, cfg)
+}
+
+func (h savedSearchHandler) Get(w http.ResponseWriter, r *http.Request) {
+	cfg := &handlerConfig{
+		Action: func() (interface{}, *errors.ServiceError) {
+			id := mux.Vars(r)["id"]
+			ctx := r.Context()
+			savedSearch, err := h.savedSearch.Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return presenters.PresentSavedSearch(savedSearch), nil
+		},
+	}
+
+	handleGet(w, r, cfg)
+}
+
+func (h savedSearchHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	cfg := &handlerConfig{
+		Action: func() (interface{}, *errors.ServiceError) {
+			id := mux.Vars(r)["id"]
+			ctx := r.Context()
+			err := h.savedSearch.Delete(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return nil, nil
+		},
+	}
+	handleDelete(w, r, cfg, http.StatusNoContent)
+}
@@ -4,7 +4,9 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/openshift-online/rh-trex/pkg/api"
 	"github.com/openshift-online/rh-trex/pkg/api/openapi"
+	"github.com/openshift-online/rh-trex/pkg/api/presenters"
 	"github.com/openshift-online/rh-trex/pkg/errors"
 )
 
@@ -55,14 +57,45 @@ func validateInclusionIn(value *string, list []string, category *string) validat
 	}
 }
 
+// validateLabels checks the keys and values of the optional *map[string]string labels field
+func validateLabels(i interface{}, fieldName string) validate {
+	return func() *errors.ServiceError {
+		labels, _ := reflect.ValueOf(i).Elem().FieldByName(fieldName).Interface().(*map[string]string)
+		if labels == nil {
+			return nil
+		}
+		for key, value := range *labels {
+			if err := api.ValidateLabelKey(key); err != nil {
+				return errors.Validation("%s", err)
+			}
+			if err := api.ValidateLabelValue(value); err != nil {
+				return errors.Validation("%s", err)
+			}
+		}
+		return nil
+	}
+}
+
 func validateDinosaurPatch(patch *openapi.DinosaurPatchRequest) validate {
 	return func() *errors.ServiceError {
-		if patch.Species == nil {
-			return errors.Validation("species cannot be nil")
+		if patch.Species == nil && patch.Labels == nil {
+			return errors.Validation("species or labels are required")
 		}
-		if len(*patch.Species) == 0 {
+		if patch.Species != nil && len(*patch.Species) == 0 {
 			return errors.Validation("species cannot be empty")
 		}
 		return nil
 	}
 }
+
+func validateSavedSearchPatch(patch *presenters.SavedSearchPatchRequest) validate {
+	return func() *errors.ServiceError {
+		if patch.Name == nil && patch.ResourceKind == nil && patch.Search == nil && patch.OrderBy == nil && patch.Fields == nil {
+			return errors.Validation("name, resource_kind, search, order_by or fields are required")
+		}
+		if patch.Name != nil && len(*patch.Name) == 0 {
+			return errors.Validation("name cannot be empty")
+		}
+		return nil
+	}
+}
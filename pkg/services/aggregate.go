@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"gorm.io/gorm/schema"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/dao"
+	"github.com/openshift-online/rh-trex/pkg/errors"
+)
+
+var aggregatePattern = regexp.MustCompile(`^(min|max)\(\s*([A-Za-z0-9_]+)\s*\)$`)
+
+// the types of the columns the resources can be grouped by. postgres has no minimum nor maximum of booleans,
+// and neither are the JSONB columns, such as the labels, groupable.
+var (
+	groupableTypes = map[schema.DataType]bool{
+		schema.Bool: true, schema.Int: true, schema.Uint: true, schema.Float: true, schema.String: true, schema.Time: true,
+	}
+	comparableTypes = map[schema.DataType]bool{
+		schema.Int: true, schema.Uint: true, schema.Float: true, schema.String: true, schema.Time: true,
+	}
+)
+
+// Aggregate groups the resources matching the search and the label selector by the args.GroupBy columns and computes
+// the args.Aggregates of each group. The columns are the ones of the resources which can be searched, see
+// SearchSchemas and SearchDisallowedFields. resourceList must be a pointer to a slice of database resource objects, it is left empty.
+func (s *sqlGenericService) Aggregate(ctx context.Context, username string, args *AggregateArguments, resourceList interface{}) ([]api.Aggregation, *errors.ServiceError) {
+	listArgs := &ListArguments{Search: args.Search, LabelSelector: args.LabelSelector, Deleted: args.Deleted}
+	listCtx, model, err := s.newListContext(ctx, username, listArgs, resourceList)
+	if err != nil {
+		return nil, err
+	}
+	listCtx.set = map[string]bool{}
+
+	d := s.genericDao.GetInstanceDao(ctx, model)
+
+	// the resources are filtered as they are listed
+	builders := []listBuilder{
+		s.buildDeleted,
+		s.buildLabelSelector,
+		s.buildSearch,
+	}
+	for _, builderFn := range builders {
+		if _, err := builderFn(listCtx, &d); err != nil {
+			return nil, err
+		}
+	}
+	// the related resources are joined to the ones they relate to, which would be counted once per relation
+	if len(listCtx.groupBy) > 0 {
+		return nil, errors.BadRequest("Aggregations can't search related resources")
+	}
+
+	resourceTable := d.GetTableName()
+	var columns, groupColumns []string
+	for _, name := range args.GroupBy {
+		column, err := aggregatedColumn(listCtx, d, name, groupableTypes)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, fmt.Sprintf("%s.%s", resourceTable, column))
+		groupColumns = append(groupColumns, column)
+	}
+
+	if len(args.Aggregates) == 0 {
+		return nil, errors.BadRequest("At least one aggregate is required")
+	}
+	var aggregates []string
+	for _, aggregate := range args.Aggregates {
+		if aggregate == AggregateCount {
+			aggregates = append(aggregates, "count(*) AS count")
+			continue
+		}
+		match := aggregatePattern.FindStringSubmatch(aggregate)
+		if match == nil {
+			return nil, errors.BadRequest("Invalid aggregate '%s', it must be %s, min(<field>) or max(<field>)", aggregate, AggregateCount)
+		}
+		column, err := aggregatedColumn(listCtx, d, match[2], comparableTypes)
+		if err != nil {
+			return nil, err
+		}
+		aggregates = append(aggregates, fmt.Sprintf("%s(%s.%s) AS %s_%s", match[1], resourceTable, column, match[1], match[2]))
+	}
+
+	var rows []map[string]interface{}
+	if err := d.Aggregate(columns, aggregates, &rows); err != nil {
+		return nil, errors.GeneralError("Unable to aggregate resources: %s", err)
+	}
+
+	aggregations := make([]api.Aggregation, 0, len(rows))
+	for _, row := range rows {
+		aggregation := api.Aggregation{Group: map[string]interface{}{}}
+		for i, name := range args.GroupBy {
+			aggregation.Group[name] = row[groupColumns[i]]
+		}
+		for _, aggregate := range args.Aggregates {
+			if aggregate == AggregateCount {
+				count, _ := row["count"].(int64)
+				aggregation.Count = &count
+				continue
+			}
+			match := aggregatePattern.FindStringSubmatch(aggregate)
+			value := row[fmt.Sprintf("%s_%s", match[1], match[2])]
+			if match[1] == "min" {
+				if aggregation.Min == nil {
+					aggregation.Min = map[string]interface{}{}
+				}
+				aggregation.Min[match[2]] = value
+			} else {
+				if aggregation.Max == nil {
+					aggregation.Max = map[string]interface{}{}
+				}
+				aggregation.Max[match[2]] = value
+			}
+		}
+		aggregations = append(aggregations, aggregation)
+	}
+	return aggregations, nil
+}
+
+// aggregatedColumn returns the column of the field the resources are aggregated over, checking that it is a column
+// of the resources, of one of the types, which can be searched
+func aggregatedColumn(listCtx *listContext, d dao.GenericDao, name string, types map[schema.DataType]bool) (string, *errors.ServiceError) {
+	if _, disallowed := (*listCtx.disallowedFields)[name]; disallowed {
+		return "", errors.BadRequest("%s is not a valid field name", name)
+	}
+	column := name
+	if listCtx.searchSchema != nil {
+		var err *errors.ServiceError
+		if column, err = listCtx.searchSchema.Column(name); err != nil {
+			return "", err
+		}
+	}
+	field, ok := d.GetColumnField(column)
+	if !ok {
+		return "", errors.BadRequest("%s is not a field of %s", name, listCtx.resourceType)
+	}
+	if !types[field.DataType] {
+		return "", errors.BadRequest("%s can't be aggregated this way", name)
+	}
+	return column, nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/auth"
+	"github.com/openshift-online/rh-trex/pkg/dao"
+	"github.com/openshift-online/rh-trex/pkg/errors"
+	"github.com/openshift-online/rh-trex/pkg/logger"
+)
+
+type AuditLogService interface {
+	Get(ctx context.Context, id string) (*api.AuditLog, *errors.ServiceError)
+	Record(ctx context.Context, auditLog *api.AuditLog) (*api.AuditLog, *errors.ServiceError)
+	PurgeBefore(ctx context.Context, before time.Time) (int64, *errors.ServiceError)
+}
+
+func NewAuditLogService(auditLogDao dao.AuditLogDao) AuditLogService {
+	return &sqlAuditLogService{
+		auditLogDao: auditLogDao,
+	}
+}
+
+var _ AuditLogService = &sqlAuditLogService{}
+
+type sqlAuditLogService struct {
+	auditLogDao dao.AuditLogDao
+}
+
+func (s *sqlAuditLogService) Get(ctx context.Context, id string) (*api.AuditLog, *errors.ServiceError) {
+	auditLog, err := s.auditLogDao.Get(ctx, id)
+	if err != nil {
+		return nil, handleGetError("AuditLog", "id", id, err)
+	}
+	return auditLog, nil
+}
+
+// Record saves an audit log. The caller's context should not carry the request transaction,
+// the calls that fail must be recorded too.
+func (s *sqlAuditLogService) Record(ctx context.Context, auditLog *api.AuditLog) (*api.AuditLog, *errors.ServiceError) {
+	auditLog, err := s.auditLogDao.Create(ctx, auditLog)
+	if err != nil {
+		return nil, handleCreateError("AuditLog", err)
+	}
+	return auditLog, nil
+}
+
+// PurgeBefore permanently deletes the audit logs created before the given time.
+func (s *sqlAuditLogService) PurgeBefore(ctx context.Context, before time.Time) (int64, *errors.ServiceError) {
+	purged, err := s.auditLogDao.PurgeBefore(ctx, before)
+	if err != nil {
+		return 0, handleDeleteError("AuditLog", err)
+	}
+	return purged, nil
+}
+
+type auditEntryKey struct{}
+
+// AuditEntry collects what a mutating call changed while it is handled,
+// the services fill it with AuditChange and the audit middleware records it once the call is over.
+type AuditEntry struct {
+	mutex sync.Mutex
+	// Actor and OrgID are the caller's, see auth.CallerRecorder
+	Actor        string
+	OrgID        string
+	ResourceKind string
+	ResourceID   string
+	Before       *string
+	After        *string
+	Changes      *string
+}
+
+var _ auth.CallerRecorder = &AuditEntry{}
+
+// WithAuditEntry returns a context in which the caller and the changes made by the services are collected
+// in the returned entry.
+func WithAuditEntry(ctx context.Context) (context.Context, *AuditEntry) {
+	entry := &AuditEntry{}
+	ctx = auth.SetCallerRecorderContext(ctx, entry)
+	return context.WithValue(ctx, auditEntryKey{}, entry), entry
+}
+
+// RecordCaller records the caller the JWT middleware authenticated.
+func (e *AuditEntry) RecordCaller(username string, orgID string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.Actor = username
+	e.OrgID = orgID
+}
+
+// AuditChange records the change of a resource in the audit entry of the context, if any.
+// before is nil for a creation and after is nil for a deletion. Both are serialized right away,
+// callers are free to modify them afterwards.
+func AuditChange(ctx context.Context, kind, id string, before, after interface{}) {
+	entry, ok := ctx.Value(auditEntryKey{}).(*AuditEntry)
+	if !ok {
+		return
+	}
+
+	beforeDoc, beforeJSON := auditDocument(ctx, before)
+	afterDoc, afterJSON := auditDocument(ctx, after)
+	changes := auditChanges(beforeDoc, afterDoc)
+	var changesJSON *string
+	if len(changes) > 0 {
+		_, changesJSON = auditDocument(ctx, changes)
+	}
+
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	entry.ResourceKind = kind
+	entry.ResourceID = id
+	entry.Before = beforeJSON
+	entry.After = afterJSON
+	entry.Changes = changesJSON
+}
+
+// Snapshot returns a copy of the collected change.
+func (e *AuditEntry) Snapshot() AuditEntry {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return AuditEntry{
+		Actor:        e.Actor,
+		OrgID:        e.OrgID,
+		ResourceKind: e.ResourceKind,
+		ResourceID:   e.ResourceID,
+		Before:       e.Before,
+		After:        e.After,
+		Changes:      e.Changes,
+	}
+}
+
+// auditDocument returns the JSON document of a resource, both decoded into a map and serialized.
+func auditDocument(ctx context.Context, resource interface{}) (map[string]interface{}, *string) {
+	if resource == nil || reflect.ValueOf(resource).Kind() == reflect.Ptr && reflect.ValueOf(resource).IsNil() {
+		return nil, nil
+	}
+	b, err := json.Marshal(resource)
+	if err != nil {
+		logger.NewOCMLogger(ctx).Warning("Unable to serialize the audited resource: " + err.Error())
+		return nil, nil
+	}
+	doc := map[string]interface{}{}
+	_ = json.Unmarshal(b, &doc)
+	s := string(b)
+	return doc, &s
+}
+
+type auditFieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// auditChanges returns the top-level fields whose values differ between the two documents.
+func auditChanges(before, after map[string]interface{}) map[string]auditFieldChange {
+	changes := map[string]auditFieldChange{}
+	for field, value := range after {
+		if previous, ok := before[field]; !ok || !reflect.DeepEqual(previous, value) {
+			changes[field] = auditFieldChange{Before: before[field], After: value}
+		}
+	}
+	for field, value := range before {
+		if _, ok := after[field]; !ok {
+			changes[field] = auditFieldChange{Before: value}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	gm "github.com/onsi/gomega"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+)
+
+func TestAuditChange(t *testing.T) {
+	gm.RegisterTestingT(t)
+
+	// without an audit entry in the context there's nothing to record
+	AuditChange(context.Background(), "Dinosaur", "1", nil, &api.Dinosaur{Species: "Stegosaurus"})
+
+	ctx, entry := WithAuditEntry(context.Background())
+	dino := &api.Dinosaur{Meta: api.Meta{ID: "1"}, Species: "Stegosaurus"}
+	before := *dino
+	dino.Species = "Triceratops"
+	AuditChange(ctx, "Dinosaur", dino.ID, &before, dino)
+	// the change is serialized right away
+	dino.Species = "Velociraptor"
+
+	change := entry.Snapshot()
+	gm.Expect(change.ResourceKind).To(gm.Equal("Dinosaur"))
+	gm.Expect(change.ResourceID).To(gm.Equal("1"))
+	gm.Expect(*change.Before).To(gm.ContainSubstring(`"Species":"Stegosaurus"`))
+	gm.Expect(*change.After).To(gm.ContainSubstring(`"Species":"Triceratops"`))
+
+	changes := map[string]auditFieldChange{}
+	gm.Expect(json.Unmarshal([]byte(*change.Changes), &changes)).To(gm.Succeed())
+	gm.Expect(changes).To(gm.Equal(map[string]auditFieldChange{
+		"Species": {Before: "Stegosaurus", After: "Triceratops"},
+	}))
+
+	// deletions have no after document, every field is reported as removed
+	var deleted *api.Dinosaur
+	AuditChange(ctx, "Dinosaur", "1", &before, deleted)
+	change = entry.Snapshot()
+	gm.Expect(change.After).To(gm.BeNil())
+	gm.Expect(*change.Changes).To(gm.ContainSubstring(`"Species":{"before":"Stegosaurus","after":null}`))
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"gorm.io/gorm/schema"
+)
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// keysetColumn is a column a list is ordered by, the id of the resources being the last one
+type keysetColumn struct {
+	column string
+	field  *schema.Field
+	desc   bool
+}
+
+// continueToken is the position in a list after its last resource: the values of the keyset columns
+// for that resource. It carries a digest of the query so that it isn't replayed with another one.
+type continueToken struct {
+	Query  string            `json:"q"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// queryDigest identifies the ordering and the filters of a list, which the continue tokens are only valid for
+func queryDigest(resourceType string, args *ListArguments) string {
+	query := strings.Join([]string{
+		resourceType,
+		strings.Join(args.OrderBy, ","),
+		args.Search,
+		args.Query,
+		args.LabelSelector,
+		fmt.Sprint(args.Deleted),
+	}, "\n")
+	digest := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(digest[:8])
+}
+
+// encodeContinueToken returns the opaque token to continue the list after the resource
+func encodeContinueToken(digest string, keyset []keysetColumn, resource reflect.Value) (string, error) {
+	token := continueToken{Query: digest}
+	for _, key := range keyset {
+		value, _ := key.field.ValueOf(resource)
+		// the valuers, such as gorm.DeletedAt, are encoded as the value they store
+		if valuer, ok := value.(driver.Valuer); ok {
+			var err error
+			if value, err = valuer.Value(); err != nil {
+				return "", err
+			}
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		token.Values = append(token.Values, b)
+	}
+	b, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeContinueToken returns the values of the keyset columns the token continues the list after,
+// nil for the NULL ones
+func decodeContinueToken(encoded string, digest string, keyset []keysetColumn) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("malformed continue token")
+	}
+	var token continueToken
+	if err := json.Unmarshal(b, &token); err != nil {
+		return nil, fmt.Errorf("malformed continue token")
+	}
+	if token.Query != digest || len(token.Values) != len(keyset) {
+		return nil, fmt.Errorf("the continue token was issued for another query")
+	}
+
+	values := make([]interface{}, len(keyset))
+	for i, key := range keyset {
+		valueType := key.field.FieldType
+		if valueType.Implements(valuerType) {
+			valueType = reflect.TypeOf((*interface{})(nil)).Elem()
+		}
+		value := reflect.New(valueType)
+		if err := json.Unmarshal(token.Values[i], value.Interface()); err != nil {
+			return nil, fmt.Errorf("malformed continue token")
+		}
+		values[i] = value.Elem().Interface()
+		if valueType.Kind() == reflect.Ptr {
+			if value.Elem().IsNil() {
+				values[i] = nil
+			} else {
+				values[i] = value.Elem().Elem().Interface()
+			}
+		}
+	}
+	return values, nil
+}
+
+// keysetSql returns the predicate selecting the resources after the values of the keyset columns,
+// in the lexicographic order of the columns. As postgres sorts them, the NULLs come after the values
+// in ascending columns and before them in descending ones.
+func keysetSql(keyset []keysetColumn, values []interface{}) (string, []any, error) {
+	after := squirrel.Or{}
+	for i, key := range keyset {
+		predicate := squirrel.And{}
+		for j := 0; j < i; j++ {
+			if values[j] == nil {
+				predicate = append(predicate, squirrel.Expr(fmt.Sprintf("%s IS NULL", keyset[j].column)))
+			} else {
+				predicate = append(predicate, squirrel.Expr(fmt.Sprintf("%s = ?", keyset[j].column), values[j]))
+			}
+		}
+		switch {
+		case key.desc && values[i] == nil:
+			predicate = append(predicate, squirrel.Expr(fmt.Sprintf("%s IS NOT NULL", key.column)))
+		case key.desc:
+			predicate = append(predicate, squirrel.Expr(fmt.Sprintf("%s < ?", key.column), values[i]))
+		case values[i] == nil:
+			// nothing comes after the NULLs of an ascending column
+			continue
+		default:
+			predicate = append(predicate, squirrel.Expr(fmt.Sprintf("(%s > ? OR %s IS NULL)", key.column, key.column), values[i]))
+		}
+		after = append(after, predicate)
+	}
+	if len(after) == 0 {
+		return "1 = 0", nil, nil
+	}
+	return after.ToSql()
+}
@@ -2,8 +2,12 @@ package services
 
 import (
 	"context"
+	e "errors"
+	"reflect"
 	"time"
 
+	"gorm.io/gorm"
+
 	"github.com/openshift-online/rh-trex/pkg/dao"
 	"github.com/openshift-online/rh-trex/pkg/db"
 	"github.com/openshift-online/rh-trex/pkg/logger"
@@ -20,9 +24,14 @@ var (
 
 type DinosaurService interface {
 	Get(ctx context.Context, id string) (*api.Dinosaur, *errors.ServiceError)
+	GetAsOf(ctx context.Context, id string, asOf time.Time) (*api.Dinosaur, *errors.ServiceError)
+	History(ctx context.Context, id string) (api.ResourceVersionList, *errors.ServiceError)
 	Create(ctx context.Context, dinosaur *api.Dinosaur) (*api.Dinosaur, *errors.ServiceError)
 	Replace(ctx context.Context, dinosaur *api.Dinosaur) (*api.Dinosaur, *errors.ServiceError)
 	Delete(ctx context.Context, id string) *errors.ServiceError
+	Restore(ctx context.Context, id string) (*api.Dinosaur, *errors.ServiceError)
+	Purge(ctx context.Context, id string) *errors.ServiceError
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, *errors.ServiceError)
 	All(ctx context.Context) (api.DinosaurList, *errors.ServiceError)
 
 	FindBySpecies(ctx context.Context, species string) (api.DinosaurList, *errors.ServiceError)
@@ -76,6 +85,30 @@ func (s *sqlDinosaurService) Get(ctx context.Context, id string) (*api.Dinosaur,
 	return dinosaur, nil
 }
 
+// GetAsOf returns the dinosaur as it was at the given time
+func (s *sqlDinosaurService) GetAsOf(ctx context.Context, id string, asOf time.Time) (*api.Dinosaur, *errors.ServiceError) {
+	dinosaur, err := s.dinosaurDao.GetAsOf(ctx, id, asOf)
+	if err != nil {
+		if e.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFound("Dinosaur with id='%s' not found as of %s", id, asOf.Format(time.RFC3339))
+		}
+		return nil, handleGetError("Dinosaur", "id", id, err)
+	}
+	return dinosaur, nil
+}
+
+// History returns every version of the dinosaur, oldest first
+func (s *sqlDinosaurService) History(ctx context.Context, id string) (api.ResourceVersionList, *errors.ServiceError) {
+	versions, err := s.dinosaurDao.History(ctx, id)
+	if err != nil {
+		return nil, handleGetError("Dinosaur", "id", id, err)
+	}
+	if len(versions) == 0 {
+		return nil, errors.NotFound("Dinosaur with id='%s' not found", id)
+	}
+	return versions, nil
+}
+
 func (s *sqlDinosaurService) Create(ctx context.Context, dinosaur *api.Dinosaur) (*api.Dinosaur, *errors.ServiceError) {
 	dinosaur, err := s.dinosaurDao.Create(ctx, dinosaur)
 	if err != nil {
@@ -88,28 +121,29 @@ func (s *sqlDinosaurService) Create(ctx context.Context, dinosaur *api.Dinosaur)
 		EventType: api.CreateEventType,
 	})
 	if eErr != nil {
-		return nil, handleCreateError("Dinosaur", err)
+		return nil, handleCreateError("Dinosaur", eErr)
 	}
 
+	AuditChange(ctx, "Dinosaur", dinosaur.ID, nil, dinosaur)
 	return dinosaur, nil
 }
 
 func (s *sqlDinosaurService) Replace(ctx context.Context, dinosaur *api.Dinosaur) (*api.Dinosaur, *errors.ServiceError) {
 	if !DisableAdvisoryLock {
-		// Updates the dinosaur species only when its species changes.
+		// Updates the dinosaur only when its species or labels change.
 		// If there are multiple requests at the same time, it will cause the race conditions among these
 		// requests (read–modify–write), the advisory lock is used here to prevent the race conditions.
 		if UseBlockingAdvisoryLock {
 			lockOwnerID, err := s.lockFactory.NewAdvisoryLock(ctx, dinosaur.ID, db.Dinosaurs)
 			if err != nil {
-				return nil, errors.DatabaseAdvisoryLock(err)
+				return nil, handleAdvisoryLockError(err)
 			}
 			defer s.lockFactory.Unlock(ctx, lockOwnerID)
 
 		} else {
 			lockOwnerID, locked, err := s.lockFactory.NewNonBlockingLock(ctx, dinosaur.ID, db.Dinosaurs)
 			if err != nil {
-				return nil, errors.DatabaseAdvisoryLock(err)
+				return nil, handleAdvisoryLockError(err)
 			}
 			if !locked {
 				return nil, handleCreateError("Dinosaur", errors.New(errors.ErrorConflict, "row locked"))
@@ -128,12 +162,20 @@ func (s *sqlDinosaurService) Replace(ctx context.Context, dinosaur *api.Dinosaur
 		time.Sleep(1 * time.Second)
 	}
 
-	// New species is no change, the update action is not needed.
-	if found.Species == dinosaur.Species {
+	// An empty species and nil labels are left as they are, no change means no update.
+	speciesChanged := dinosaur.Species != "" && found.Species != dinosaur.Species
+	labelsChanged := dinosaur.Labels != nil && !reflect.DeepEqual(map[string]string(found.Labels), map[string]string(dinosaur.Labels))
+	if !speciesChanged && !labelsChanged {
 		return found, nil
 	}
 
-	found.Species = dinosaur.Species
+	before := *found
+	if speciesChanged {
+		found.Species = dinosaur.Species
+	}
+	if labelsChanged {
+		found.Labels = dinosaur.Labels
+	}
 	updated, err := s.dinosaurDao.Replace(ctx, found)
 	if err != nil {
 		return nil, handleUpdateError("Dinosaur", err)
@@ -145,14 +187,19 @@ func (s *sqlDinosaurService) Replace(ctx context.Context, dinosaur *api.Dinosaur
 		EventType: api.UpdateEventType,
 	})
 	if eErr != nil {
-		return nil, handleUpdateError("Dinosaur", err)
+		return nil, handleUpdateError("Dinosaur", eErr)
 	}
+
+	AuditChange(ctx, "Dinosaur", updated.ID, &before, updated)
 	return updated, nil
 }
 
 func (s *sqlDinosaurService) Delete(ctx context.Context, id string) *errors.ServiceError {
+	// only read for the audit log, deleting a missing dinosaur is not an error
+	before, _ := s.dinosaurDao.Get(ctx, id)
+
 	if err := s.dinosaurDao.Delete(ctx, id); err != nil {
-		return handleDeleteError("Dinosaur", errors.GeneralError("Unable to delete dinosaur: %s", err))
+		return handleDeleteError("Dinosaur", err)
 	}
 
 	_, err := s.events.Create(ctx, &api.Event{
@@ -164,9 +211,71 @@ func (s *sqlDinosaurService) Delete(ctx context.Context, id string) *errors.Serv
 		return handleDeleteError("Dinosaur", err)
 	}
 
+	AuditChange(ctx, "Dinosaur", id, before, nil)
 	return nil
 }
 
+// Restore undeletes a soft-deleted dinosaur, the controllers see it as updated.
+func (s *sqlDinosaurService) Restore(ctx context.Context, id string) (*api.Dinosaur, *errors.ServiceError) {
+	if err := s.dinosaurDao.Restore(ctx, id); err != nil {
+		if e.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFound("Deleted Dinosaur with id='%s' not found", id)
+		}
+		return nil, handleUpdateError("Dinosaur", err)
+	}
+
+	_, err := s.events.Create(ctx, &api.Event{
+		Source:    "Dinosaurs",
+		SourceID:  id,
+		EventType: api.UpdateEventType,
+	})
+	if err != nil {
+		return nil, handleUpdateError("Dinosaur", err)
+	}
+
+	restored, svcErr := s.Get(ctx, id)
+	if svcErr != nil {
+		return nil, svcErr
+	}
+	AuditChange(ctx, "Dinosaur", id, nil, restored)
+	return restored, nil
+}
+
+// Purge permanently deletes a dinosaur. Deleting a live dinosaur emits a delete event as Delete does,
+// the controllers have already handled the deletion of a soft-deleted one.
+func (s *sqlDinosaurService) Purge(ctx context.Context, id string) *errors.ServiceError {
+	wasDeleted, err := s.dinosaurDao.Purge(ctx, id)
+	if err != nil {
+		if e.Is(err, gorm.ErrRecordNotFound) {
+			return handleGetError("Dinosaur", "id", id, err)
+		}
+		return handleDeleteError("Dinosaur", err)
+	}
+	AuditChange(ctx, "Dinosaur", id, nil, nil)
+	if wasDeleted {
+		return nil
+	}
+
+	_, eErr := s.events.Create(ctx, &api.Event{
+		Source:    "Dinosaurs",
+		SourceID:  id,
+		EventType: api.DeleteEventType,
+	})
+	if eErr != nil {
+		return handleDeleteError("Dinosaur", eErr)
+	}
+	return nil
+}
+
+// PurgeDeleted permanently deletes the dinosaurs soft-deleted before the given time.
+func (s *sqlDinosaurService) PurgeDeleted(ctx context.Context, before time.Time) (int64, *errors.ServiceError) {
+	purged, err := s.dinosaurDao.PurgeDeleted(ctx, before)
+	if err != nil {
+		return 0, handleDeleteError("Dinosaur", err)
+	}
+	return purged, nil
+}
+
 func (s *sqlDinosaurService) FindByIDs(ctx context.Context, ids []string) (api.DinosaurList, *errors.ServiceError) {
 	dinosaurs, err := s.dinosaurDao.FindByIDs(ctx, ids)
 	if err != nil {
@@ -56,7 +56,7 @@ func (s *sqlEventService) Replace(ctx context.Context, event *api.Event) (*api.E
 
 func (s *sqlEventService) Delete(ctx context.Context, id string) *errors.ServiceError {
 	if err := s.eventDao.Delete(ctx, id); err != nil {
-		return handleDeleteError("Event", errors.GeneralError("Unable to delete event: %s", err))
+		return handleDeleteError("Event", err)
 	}
 	return nil
 }
@@ -15,6 +15,8 @@ import (
 	sqlFilter "github.com/yaacov/tree-search-language/pkg/walkers/sql"
 
 	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/auth"
+	"github.com/openshift-online/rh-trex/pkg/config"
 	"github.com/openshift-online/rh-trex/pkg/dao"
 	"github.com/openshift-online/rh-trex/pkg/db"
 	"github.com/openshift-online/rh-trex/pkg/errors"
@@ -23,21 +25,40 @@ import (
 
 type GenericService interface {
 	List(ctx context.Context, username string, args *ListArguments, resourceList interface{}) (*api.PagingMeta, *errors.ServiceError)
+	Export(ctx context.Context, username string, args *ListArguments, resourceList interface{}, each func(resource interface{}) error) *errors.ServiceError
+	Aggregate(ctx context.Context, username string, args *AggregateArguments, resourceList interface{}) ([]api.Aggregation, *errors.ServiceError)
+	Include(ctx context.Context, include []string, resource interface{}) *errors.ServiceError
+	ExpandView(ctx context.Context, args *ListArguments, resourceList interface{}) *errors.ServiceError
 }
 
-func NewGenericService(genericDao dao.GenericDao) GenericService {
-	return &sqlGenericService{genericDao: genericDao}
+// NewGenericService returns the generic service, whose lists are rejected when their queries exceed the limits,
+// none when nil
+func NewGenericService(genericDao dao.GenericDao, queryLimits *config.QueryLimitsConfig) GenericService {
+	return &sqlGenericService{genericDao: genericDao, queryLimits: queryLimits}
 }
 
 var _ GenericService = &sqlGenericService{}
 
 type sqlGenericService struct {
-	genericDao dao.GenericDao
+	genericDao  dao.GenericDao
+	queryLimits *config.QueryLimitsConfig
 }
 
 var (
 	SearchDisallowedFields = map[string]map[string]string{}
 	allFieldsAllowed       = map[string]string{}
+
+	// FullTextSearchColumns are the tsvector columns the full-text queries search, by resource type.
+	// The kinds without one can't be searched by text.
+	FullTextSearchColumns = map[string]string{}
+
+	// SearchSchemas are the fields the searches, the orderings and the aggregations can use, by resource type.
+	// The kinds without one can use any of their columns which isn't in SearchDisallowedFields.
+	SearchSchemas = map[string]*db.SearchSchema{}
+
+	// Relations are the related kinds the lists and the gets can include, by resource type and by the name
+	// of the relation in the include parameter.
+	Relations = map[string]map[string]Relation{}
 )
 
 // wrap all needed pieces for the LIST funciton
@@ -49,10 +70,15 @@ type listContext struct {
 	ulog             *logger.OCMLogger
 	resourceList     interface{}
 	disallowedFields *map[string]string
+	searchSchema     *db.SearchSchema
 	resourceType     string
 	joins            map[string]dao.TableRelation
 	groupBy          []string
 	set              map[string]bool
+	// keyset is the columns the resources are ordered by, nil when they can't be continued after one
+	keyset []keysetColumn
+	// after selects the resources after the continue token
+	after *dao.Where
 }
 
 func (s *sqlGenericService) newListContext(ctx context.Context, username string, args *ListArguments, resourceList interface{}) (*listContext, interface{}, *errors.ServiceError) {
@@ -75,6 +101,7 @@ func (s *sqlGenericService) newListContext(ctx context.Context, username string,
 		ulog:             &log,
 		resourceList:     resourceList,
 		disallowedFields: &disallowedFields,
+		searchSchema:     searchSchema(resourceTypeStr),
 		resourceType:     resourceTypeStr,
 	}, reflect.New(resourceModel).Interface(), nil
 }
@@ -94,6 +121,21 @@ func (s *sqlGenericService) List(ctx context.Context, username string, args *Lis
 		// add "ORDER BY"
 		s.buildOrderBy,
 
+		// narrow "SELECT" to the columns of the requested fields.
+		s.buildSelect,
+
+		// select the soft-deleted resources instead of the live ones when asked to.
+		s.buildDeleted,
+
+		// translate "labelSelector" into "WHERE"(s) on the labels.
+		s.buildLabelSelector,
+
+		// translate "q" into a "WHERE" matching the full-text search column.
+		s.buildTextSearch,
+
+		// continue the list after the resource of the "continue" token.
+		s.buildContinue,
+
 		// translate "search" into "WHERE"(s), and "JOIN"(s) if related resource is searched.
 		s.buildSearch,
 
@@ -119,35 +161,203 @@ func (s *sqlGenericService) List(ctx context.Context, username string, args *Lis
 	return listCtx.pagingMeta, nil
 }
 
+// Export runs the list without paging it: the resources are loaded one at a time from a database cursor and passed
+// to each, in the order of the list, for exports too large to be loaded at once. The preloads, the continue tokens
+// and the totals don't apply to exports. resourceList must be a pointer to a slice of database resource objects,
+// it is left empty.
+func (s *sqlGenericService) Export(ctx context.Context, username string, args *ListArguments, resourceList interface{}, each func(resource interface{}) error) *errors.ServiceError {
+	listCtx, model, err := s.newListContext(ctx, username, args, resourceList)
+	if err != nil {
+		return err
+	}
+	listCtx.set = map[string]bool{}
+
+	builders := []listBuilder{
+		s.buildOrderBy,
+		s.buildSelect,
+		s.buildDeleted,
+		s.buildLabelSelector,
+		s.buildTextSearch,
+		s.buildSearch,
+	}
+
+	d := s.genericDao.GetInstanceDao(ctx, model)
+	for _, builderFn := range builders {
+		if _, err := builderFn(listCtx, &d); err != nil {
+			return err
+		}
+	}
+
+	// the exports aren't paged, the query of all the resources must not cost too much
+	if err := s.guardPlan(listCtx, &d, 0, 0); err != nil {
+		return err
+	}
+
+	resourceModel := reflect.TypeOf(model).Elem()
+	newResource := func() interface{} {
+		return reflect.New(resourceModel).Interface()
+	}
+	if err := d.Stream(newResource, each); err != nil {
+		return errors.GeneralError("Unable to export resources: %s", err)
+	}
+	return nil
+}
+
 /*** Define all sub functions in the type of listBuilder ***/
 type listBuilder func(*listContext, *dao.GenericDao) (finished bool, err *errors.ServiceError)
 
 func (s *sqlGenericService) buildPreload(listCtx *listContext, d *dao.GenericDao) (bool, *errors.ServiceError) {
 	listCtx.set = make(map[string]bool)
 
-	for _, preload := range listCtx.args.Preloads {
-		listCtx.set[preload] = true
+	// the preloads are the relations the list includes, loaded by their associations
+	associations, err := includedAssociations(listCtx.resourceType, listCtx.args.Preloads)
+	if err != nil {
+		return false, err
+	}
+	for _, association := range associations {
+		listCtx.set[association] = true
 	}
 	// preload each table only once; struct{} doesn't occupy any additional space
-	for _, preload := range listCtx.args.Preloads {
-		(*d).Preload(preload)
+	for association := range listCtx.set {
+		(*d).Preload(association)
 	}
 	return false, nil
 }
 
 func (s *sqlGenericService) buildOrderBy(listCtx *listContext, d *dao.GenericDao) (bool, *errors.ServiceError) {
+	resourceTable := (*d).GetTableName()
+	keyset := []keysetColumn{}
+	orderedById := false
+	// the full-text searches are ordered by relevance unless asked otherwise, which can't be continued after
+	if column, ok := FullTextSearchColumns[listCtx.resourceType]; ok && listCtx.args.Query != "" && len(listCtx.args.OrderBy) == 0 {
+		(*d).OrderBy(db.TextSearchRank(fmt.Sprintf("%s.%s", resourceTable, column), listCtx.args.Query))
+		keyset = nil
+	}
 	if len(listCtx.args.OrderBy) != 0 {
-		orderByArgs, serviceErr := db.ArgsToOrderBy(listCtx.args.OrderBy, *listCtx.disallowedFields)
+		orderByArgs, serviceErr := db.ArgsToOrderBy(listCtx.args.OrderBy, *listCtx.disallowedFields, listCtx.searchSchema)
 		if serviceErr != nil {
 			return false, serviceErr
 		}
 		for _, orderByArg := range orderByArgs {
 			(*d).OrderBy(orderByArg)
+
+			// only the columns of the resource can be continued after
+			i := strings.LastIndex(orderByArg, " ")
+			column := strings.TrimPrefix(orderByArg[:i], resourceTable+".")
+			field, ok := (*d).GetColumnField(column)
+			if keyset == nil || !ok {
+				keyset = nil
+				continue
+			}
+			keyset = append(keyset, keysetColumn{column: fmt.Sprintf("%s.%s", resourceTable, column), field: field, desc: orderByArg[i+1:] == "desc"})
+			orderedById = orderedById || column == "id"
+		}
+	}
+	// the ids break the ties, for the resources to have a stable order across pages
+	if !orderedById {
+		(*d).OrderBy(fmt.Sprintf("%s.id asc", resourceTable))
+		if field, ok := (*d).GetColumnField("id"); ok && keyset != nil {
+			keyset = append(keyset, keysetColumn{column: fmt.Sprintf("%s.id", resourceTable), field: field})
+		} else {
+			keyset = nil
+		}
+	}
+	listCtx.keyset = keyset
+	return false, nil
+}
+
+// buildSelect selects the columns of the fields requested, by their json names which are the names of the columns,
+// with the ids and the columns the resources are ordered by. The fields of the nested structures select the column
+// of the structure, and the ones which aren't columns are left for presenters.SliceFilter to reject or derive,
+// such as the kind and the href from the id. The preloads need the foreign keys, they select all the columns.
+func (s *sqlGenericService) buildSelect(listCtx *listContext, d *dao.GenericDao) (bool, *errors.ServiceError) {
+	if len(listCtx.args.Fields) == 0 || len(listCtx.args.Preloads) != 0 {
+		return false, nil
+	}
+	resourceTable := (*d).GetTableName()
+	columns := []string{fmt.Sprintf("%s.id", resourceTable)}
+	selected := map[string]bool{columns[0]: true}
+	for _, key := range listCtx.keyset {
+		if !selected[key.column] {
+			columns = append(columns, key.column)
+			selected[key.column] = true
+		}
+	}
+	for _, field := range listCtx.args.Fields {
+		name := strings.Split(field, ".")[0]
+		if _, ok := (*d).GetColumnField(name); !ok {
+			continue
+		}
+		column := fmt.Sprintf("%s.%s", resourceTable, name)
+		if !selected[column] {
+			columns = append(columns, column)
+			selected[column] = true
+		}
+	}
+	(*d).Select(columns)
+	return false, nil
+}
+
+func (s *sqlGenericService) buildContinue(listCtx *listContext, d *dao.GenericDao) (bool, *errors.ServiceError) {
+	if listCtx.args.Continue == "" {
+		return false, nil
+	}
+	if listCtx.keyset == nil {
+		return false, errors.BadRequest("Lists ordered by %s can't be continued", strings.Join(listCtx.args.OrderBy, ","))
+	}
+	values, err := decodeContinueToken(listCtx.args.Continue, queryDigest(listCtx.resourceType, listCtx.args), listCtx.keyset)
+	if err != nil {
+		return false, errors.BadRequest("Invalid continue token: %s", err)
+	}
+	sql, sqlValues, err := keysetSql(listCtx.keyset, values)
+	if err != nil {
+		return false, errors.GeneralError("%s", err.Error())
+	}
+	after := dao.NewWhere(sql, sqlValues)
+	listCtx.after = &after
+	return false, nil
+}
+
+// buildDeleted selects the soft-deleted resources instead of the live ones, which only administrators can list
+func (s *sqlGenericService) buildDeleted(listCtx *listContext, d *dao.GenericDao) (bool, *errors.ServiceError) {
+	if listCtx.args.Deleted {
+		if !auth.IsAdmin(listCtx.ctx) {
+			return false, errors.Forbidden("Only administrators can list deleted %s resources", listCtx.resourceType)
 		}
+		(*d).Unscoped()
+		(*d).Where(dao.NewWhere(fmt.Sprintf("%s.deleted_at IS NOT NULL", (*d).GetTableName()), nil))
 	}
 	return false, nil
 }
 
+func (s *sqlGenericService) buildLabelSelector(listCtx *listContext, d *dao.GenericDao) (bool, *errors.ServiceError) {
+	if listCtx.args.LabelSelector == "" {
+		return false, nil
+	}
+	requirements, err := ParseLabelSelector(listCtx.args.LabelSelector)
+	if err != nil {
+		return false, errors.BadRequest("Failed to parse label selector: %s", err)
+	}
+	sql, values, err := labelSelectorSql(fmt.Sprintf("%s.labels", (*d).GetTableName()), requirements)
+	if err != nil {
+		return false, errors.GeneralError("%s", err.Error())
+	}
+	(*d).Where(dao.NewWhere(sql, values))
+	return false, nil
+}
+
+func (s *sqlGenericService) buildTextSearch(listCtx *listContext, d *dao.GenericDao) (bool, *errors.ServiceError) {
+	if listCtx.args.Query == "" {
+		return false, nil
+	}
+	column, ok := FullTextSearchColumns[listCtx.resourceType]
+	if !ok {
+		return false, errors.BadRequest("%s can't be searched by text", listCtx.resourceType)
+	}
+	(*d).Where(dao.NewWhere(db.TextSearchMatch(fmt.Sprintf("%s.%s", (*d).GetTableName(), column)), []any{listCtx.args.Query}))
+	return false, nil
+}
+
 func (s *sqlGenericService) buildSearchValues(listCtx *listContext, d *dao.GenericDao) (string, []any, *errors.ServiceError) {
 	if listCtx.args.Search == "" {
 		s.addJoins(listCtx, d)
@@ -159,11 +369,17 @@ func (s *sqlGenericService) buildSearchValues(listCtx *listContext, d *dao.Gener
 	if err != nil {
 		return "", nil, errors.BadRequest("Failed to parse search query: %s", listCtx.args.Search)
 	}
+	if serviceErr := s.guardSearch(listCtx, tslTree); serviceErr != nil {
+		return "", nil, serviceErr
+	}
 	// find all related tables
 	tslTree, serviceErr := s.treeWalkForRelatedTables(listCtx, tslTree, d)
 	if serviceErr != nil {
 		return "", nil, serviceErr
 	}
+	if serviceErr := s.guardJoins(listCtx); serviceErr != nil {
+		return "", nil, serviceErr
+	}
 	// prepend table names to prevent "ambiguous" errors
 	tslTree, serviceErr = s.treeWalkForAddingTableName(listCtx, tslTree, d)
 	if serviceErr != nil {
@@ -204,7 +420,14 @@ func (s *sqlGenericService) addJoins(listCtx *listContext, d *dao.GenericDao) {
 		sql := fmt.Sprintf(
 			"LEFT JOIN %s ON %s.%s = %s.%s AND %s.deleted_at IS NULL",
 			r.ForeignTableName, r.ForeignTableName, r.ForeignColumnName, r.TableName, r.ColumnName, r.ForeignTableName)
-		(*d).Joins(sql)
+		// the tenant scope only confines the table of the list, the searches mustn't match the related records
+		// of other organizations
+		var values []any
+		if orgID, confined := db.ConfiningTenant(listCtx.ctx); confined && r.ForeignTenantColumn != "" {
+			sql += fmt.Sprintf(" AND %s.%s = ?", r.ForeignTableName, r.ForeignTenantColumn)
+			values = append(values, orgID)
+		}
+		(*d).Joins(sql, values...)
 
 		listCtx.groupBy = append(listCtx.groupBy, r.ForeignTableName+".id")
 		listCtx.set[r.ForeignTableName] = true
@@ -223,7 +446,25 @@ func (s *sqlGenericService) loadList(listCtx *listContext, d *dao.GenericDao) *e
 	args := listCtx.args
 	ulog := *listCtx.ulog
 
-	(*d).Count(listCtx.resourceList, &listCtx.pagingMeta.Total)
+	switch args.Total {
+	case "", TotalExact:
+		(*d).Count(listCtx.resourceList, &listCtx.pagingMeta.Total)
+	case TotalEstimate:
+		if err := (*d).EstimateCount(listCtx.resourceList, &listCtx.pagingMeta.Total); err != nil {
+			return errors.GeneralError("Unable to estimate the number of resources: %s", err)
+		}
+	case TotalNone:
+		listCtx.pagingMeta.Total = -1
+	default:
+		return errors.BadRequest("Invalid total '%s', it must be one of %s, %s or %s", args.Total, TotalExact, TotalEstimate, TotalNone)
+	}
+
+	// the continued lists start after the resource of the token, the total still counts them all
+	offset := (args.Page - 1) * int(args.Size)
+	if listCtx.after != nil {
+		(*d).Where(*listCtx.after)
+		offset = 0
+	}
 
 	// Set resourceList to be an empty slice with zero capacity. Real space will be allocated by g2.Find()
 	if err := zeroSlice(listCtx.resourceList, 0); err != nil {
@@ -242,9 +483,14 @@ func (s *sqlGenericService) loadList(listCtx *listContext, d *dao.GenericDao) *e
 		return nil
 	}
 
+	// the queries costing too much are rejected before they run
+	if err := s.guardPlan(listCtx, d, offset, int(args.Size)); err != nil {
+		return err
+	}
+
 	// NOTE: Limit no longer supports '0' size and will cause issues. There is an early return, do not remove it.
 	//       https://github.com/go-gorm/gorm/blob/master/clause/limit.go#L18-L21
-	if err := (*d).Fetch((args.Page-1)*int(args.Size), int(args.Size), listCtx.resourceList); err != nil {
+	if err := (*d).Fetch(offset, int(args.Size), listCtx.resourceList); err != nil {
 		if e.Is(err, gorm.ErrRecordNotFound) {
 			listCtx.pagingMeta.Size = 0
 		} else {
@@ -253,6 +499,16 @@ func (s *sqlGenericService) loadList(listCtx *listContext, d *dao.GenericDao) *e
 	}
 	listCtx.pagingMeta.Size = int64(reflect.ValueOf(listCtx.resourceList).Elem().Len())
 
+	// a full page may be followed by more resources
+	if listCtx.pagingMeta.Size == args.Size && listCtx.keyset != nil {
+		last := reflect.ValueOf(listCtx.resourceList).Elem().Index(int(args.Size) - 1)
+		token, err := encodeContinueToken(queryDigest(listCtx.resourceType, args), listCtx.keyset, reflect.Indirect(last))
+		if err != nil {
+			return errors.GeneralError("Unable to build the continue token: %s", err)
+		}
+		listCtx.pagingMeta.Continue = token
+	}
+
 	return nil
 }
 
@@ -281,7 +537,7 @@ func (s *sqlGenericService) treeWalkForRelatedTables(listCtx *listContext, tslTr
 	}
 	walkFn := func(field string) (string, error) {
 		fieldParts := strings.Split(field, ".")
-		if len(fieldParts) > 1 && fieldParts[0] != resourceTable {
+		if len(fieldParts) > 1 && fieldParts[0] != resourceTable && fieldParts[0] != db.PropertiesColumn {
 			fieldName := fieldParts[0]
 			_, exists := listCtx.joins[fieldName]
 			if !exists {
@@ -312,10 +568,7 @@ func (s *sqlGenericService) treeWalkForAddingTableName(listCtx *listContext, tsl
 
 	walkFn := func(field string) (string, error) {
 		fieldParts := strings.Split(field, ".")
-		if len(fieldParts) == 1 {
-			if strings.Contains(field, "->") {
-				return field, nil
-			}
+		if len(fieldParts) == 1 || fieldParts[0] == db.PropertiesColumn {
 			return fmt.Sprintf("%s.%s", resourceTable, field), nil
 		}
 		return field, nil
@@ -331,7 +584,7 @@ func (s *sqlGenericService) treeWalkForAddingTableName(listCtx *listContext, tsl
 
 func (s *sqlGenericService) treeWalkForSqlizer(listCtx *listContext, tslTree tsl.Node) (tsl.Node, squirrel.Sqlizer, *errors.ServiceError) {
 	// Check field names in tree
-	tslTree, serviceErr := db.FieldNameWalk(tslTree, *listCtx.disallowedFields)
+	tslTree, serviceErr := db.FieldNameWalk(tslTree, *listCtx.disallowedFields, listCtx.searchSchema)
 	if serviceErr != nil {
 		return tslTree, nil, serviceErr
 	}
@@ -2,14 +2,20 @@ package services
 
 import (
 	"context"
+	"reflect"
+	"sync"
 	"testing"
 
+	"github.com/openshift-online/rh-trex/pkg/config"
 	"github.com/openshift-online/rh-trex/pkg/dao"
+	"github.com/openshift-online/rh-trex/pkg/dao/mocks"
 	"github.com/openshift-online/rh-trex/pkg/db"
 	dbmocks "github.com/openshift-online/rh-trex/pkg/db/mocks"
 
 	"github.com/onsi/gomega/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/yaacov/tree-search-language/pkg/tsl"
+	"gorm.io/gorm/schema"
 
 	"github.com/openshift-online/rh-trex/pkg/api"
 	"github.com/openshift-online/rh-trex/pkg/errors"
@@ -42,6 +48,7 @@ func TestSQLTranslation(t *testing.T) {
 		errorMsg := test["error"].(string)
 		listCtx, model, serviceErr := genericService.newListContext(context.Background(), "", &ListArguments{Search: search}, &list)
 		Expect(serviceErr).ToNot(HaveOccurred())
+		listCtx.set = map[string]bool{}
 		d := g.GetInstanceDao(context.Background(), model)
 		(*listCtx.disallowedFields)["id"] = "id"
 		_, serviceErr = genericService.buildSearch(listCtx, &d)
@@ -75,3 +82,352 @@ func TestSQLTranslation(t *testing.T) {
 		Expect(values).To(valuesReal)
 	}
 }
+
+func TestLabelSelectorTranslation(t *testing.T) {
+	RegisterTestingT(t)
+
+	tests := []map[string]interface{}{
+		{
+			"selector": "tier=gold",
+			"sql":      "(labels @> ?::jsonb)",
+			"values":   ConsistOf(`{"tier":"gold"}`),
+		},
+		{
+			"selector": "tier==gold, !deprecated",
+			"sql":      "(labels @> ?::jsonb AND labels->>? IS NULL)",
+			"values":   ConsistOf(`{"tier":"gold"}`, "deprecated"),
+		},
+		{
+			"selector": "example.com/tier != gold",
+			"sql":      "(NOT (labels @> ?::jsonb))",
+			"values":   ConsistOf(`{"example.com/tier":"gold"}`),
+		},
+		{
+			"selector": "tier in (gold, silver),team",
+			"sql":      "(labels->>? IN (?,?) AND labels->>? IS NOT NULL)",
+			"values":   Equal([]any{"tier", "gold", "silver", "team"}),
+		},
+		{
+			"selector": "tier notin (bronze)",
+			"sql":      "((labels->>? IS NULL OR labels->>? NOT IN (?)))",
+			"values":   Equal([]any{"tier", "tier", "bronze"}),
+		},
+	}
+	for _, test := range tests {
+		requirements, err := ParseLabelSelector(test["selector"].(string))
+		Expect(err).ToNot(HaveOccurred())
+		sql, values, err := labelSelectorSql("labels", requirements)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sql).To(Equal(test["sql"].(string)))
+		Expect(values).To(test["values"].(types.GomegaMatcher))
+	}
+
+	// ill-formatted selectors should be rejected
+	for _, selector := range []string{"tier=gold,", "-tier", "tier=gold!", "tier in ()", "x/y/=z"} {
+		_, err := ParseLabelSelector(selector)
+		Expect(err).To(HaveOccurred(), "selector %q", selector)
+	}
+}
+
+func TestPropertiesSearchTranslation(t *testing.T) {
+	RegisterTestingT(t)
+	var dbFactory db.SessionFactory = dbmocks.NewMockSessionFactory()
+	defer dbFactory.Close()
+
+	g := dao.NewGenericDao(&dbFactory)
+	genericService := sqlGenericService{genericDao: g}
+
+	tests := []map[string]interface{}{
+		{
+			"search": "properties.color = 'red'",
+			"sql":    "dinosaurs.properties #>> '{color}' = ?",
+			"values": ConsistOf("red"),
+		},
+		{
+			"search": "properties.size.height > 3 and properties.size.height <= 10",
+			"sql":    "((CASE WHEN jsonb_typeof(dinosaurs.properties #> '{size,height}') = 'number' THEN (dinosaurs.properties #>> '{size,height}')::numeric END) > ? AND (CASE WHEN jsonb_typeof(dinosaurs.properties #> '{size,height}') = 'number' THEN (dinosaurs.properties #>> '{size,height}')::numeric END) <= ?)",
+			"values": ConsistOf(float64(3), float64(10)),
+		},
+		{
+			"search": `properties."habitat.region.name" in ('north', 'south')`,
+			"sql":    "dinosaurs.properties #>> '{habitat,region,name}' IN (?,?)",
+			"values": ConsistOf("north", "south"),
+		},
+		{
+			"search": "dinosaurs.properties.extinct = 'true'",
+			"sql":    "(CASE WHEN jsonb_typeof(dinosaurs.properties #> '{extinct}') = 'boolean' THEN (dinosaurs.properties #>> '{extinct}')::boolean END) = ?",
+			"values": ConsistOf("true"),
+		},
+		{
+			"search": "properties.fossil is not null",
+			"sql":    "dinosaurs.properties #> '{fossil}' IS NOT NULL",
+			"values": BeEmpty(),
+		},
+	}
+	for _, test := range tests {
+		var list []api.Dinosaur
+		listCtx, model, serviceErr := genericService.newListContext(context.Background(), "", &ListArguments{Search: test["search"].(string)}, &list)
+		Expect(serviceErr).ToNot(HaveOccurred())
+		listCtx.set = map[string]bool{}
+		d := g.GetInstanceDao(context.Background(), model)
+		sql, values, serviceErr := genericService.buildSearchValues(listCtx, &d)
+		Expect(serviceErr).ToNot(HaveOccurred(), "search %q", test["search"])
+		Expect(sql).To(Equal(test["sql"].(string)))
+		Expect(values).To(test["values"].(types.GomegaMatcher))
+	}
+
+	// the properties of the joined resources are qualified with their table
+	tslTree, err := tsl.ParseTSL("fossils.properties.age > 65")
+	Expect(err).ToNot(HaveOccurred())
+	tslTree, serviceErr := db.FieldNameWalk(tslTree, map[string]string{}, nil)
+	Expect(serviceErr).ToNot(HaveOccurred())
+	Expect(tslTree.Left.(tsl.Node).Left).To(HavePrefix("(CASE WHEN jsonb_typeof(fossils.properties #> '{age}')"))
+
+	// property keys are embedded in the SQL and must be plain names
+	var list []api.Dinosaur
+	listCtx, model, serviceErr := genericService.newListContext(context.Background(), "", &ListArguments{Search: `properties."color'--" = 'red'`}, &list)
+	Expect(serviceErr).ToNot(HaveOccurred())
+	d := g.GetInstanceDao(context.Background(), model)
+	_, _, serviceErr = genericService.buildSearchValues(listCtx, &d)
+	Expect(serviceErr).To(HaveOccurred())
+	Expect(serviceErr.Code).To(Equal(errors.ErrorBadRequest))
+}
+
+func TestContinueToken(t *testing.T) {
+	RegisterTestingT(t)
+
+	dinosaurSchema, err := schema.Parse(&api.Dinosaur{}, &sync.Map{}, schema.NamingStrategy{})
+	Expect(err).ToNot(HaveOccurred())
+	keyset := []keysetColumn{
+		{column: "dinosaurs.species", field: dinosaurSchema.FieldsByDBName["species"], desc: true},
+		{column: "dinosaurs.deleted_at", field: dinosaurSchema.FieldsByDBName["deleted_at"]},
+		{column: "dinosaurs.id", field: dinosaurSchema.FieldsByDBName["id"]},
+	}
+	args := &ListArguments{OrderBy: []string{"species desc", "deleted_at"}}
+	digest := queryDigest("Dinosaur", args)
+
+	dinosaur := api.Dinosaur{Meta: api.Meta{ID: "abc"}, Species: "T-Rex"}
+	token, err := encodeContinueToken(digest, keyset, reflect.ValueOf(dinosaur))
+	Expect(err).ToNot(HaveOccurred())
+
+	values, err := decodeContinueToken(token, digest, keyset)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(values).To(Equal([]interface{}{"T-Rex", nil, "abc"}))
+
+	sql, sqlValues, err := keysetSql(keyset, values)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(sql).To(Equal("((dinosaurs.species < ?) OR (dinosaurs.species = ? AND dinosaurs.deleted_at IS NULL AND (dinosaurs.id > ? OR dinosaurs.id IS NULL)))"))
+	Expect(sqlValues).To(Equal([]any{"T-Rex", "T-Rex", "abc"}))
+
+	// the tokens are only valid for the query they were issued for
+	_, err = decodeContinueToken(token, queryDigest("Dinosaur", &ListArguments{OrderBy: []string{"species asc"}}), keyset)
+	Expect(err).To(HaveOccurred())
+	_, err = decodeContinueToken("garbage", digest, keyset)
+	Expect(err).To(HaveOccurred())
+}
+
+func TestAggregateValidation(t *testing.T) {
+	RegisterTestingT(t)
+	var dbFactory db.SessionFactory = dbmocks.NewMockSessionFactory()
+	defer dbFactory.Close()
+
+	genericService := sqlGenericService{genericDao: dao.NewGenericDao(&dbFactory)}
+
+	// the groups and aggregates are rejected before reaching the database
+	tests := []struct {
+		args  AggregateArguments
+		error string
+	}{
+		{AggregateArguments{GroupBy: []string{"teeth"}, Aggregates: []string{"count"}}, "rh-trex-21: teeth is not a field of Dinosaur"},
+		{AggregateArguments{GroupBy: []string{"labels"}, Aggregates: []string{"count"}}, "rh-trex-21: labels can't be aggregated this way"},
+		{AggregateArguments{Aggregates: []string{"sum(species)"}}, "rh-trex-21: Invalid aggregate 'sum(species)', it must be count, min(<field>) or max(<field>)"},
+		{AggregateArguments{Aggregates: []string{"max(species); drop table dinosaurs"}}, "rh-trex-21: Invalid aggregate 'max(species); drop table dinosaurs', it must be count, min(<field>) or max(<field>)"},
+		{AggregateArguments{}, "rh-trex-21: At least one aggregate is required"},
+		{AggregateArguments{Search: "garbage", Aggregates: []string{"count"}}, "rh-trex-21: Failed to parse search query: garbage"},
+	}
+	for _, test := range tests {
+		var list []api.Dinosaur
+		_, serviceErr := genericService.Aggregate(context.Background(), "", &test.args, &list)
+		Expect(serviceErr).To(HaveOccurred())
+		Expect(serviceErr.Code).To(Equal(errors.ErrorBadRequest))
+		Expect(serviceErr.Error()).To(Equal(test.error))
+	}
+}
+
+func TestTextSearch(t *testing.T) {
+	RegisterTestingT(t)
+	var dbFactory db.SessionFactory = dbmocks.NewMockSessionFactory()
+	defer dbFactory.Close()
+
+	g := dao.NewGenericDao(&dbFactory)
+	genericService := sqlGenericService{genericDao: g}
+
+	// only the kinds with a search column can be searched by text
+	var list []api.Dinosaur
+	listCtx, model, serviceErr := genericService.newListContext(context.Background(), "", &ListArguments{Query: "rex"}, &list)
+	Expect(serviceErr).ToNot(HaveOccurred())
+	d := g.GetInstanceDao(context.Background(), model)
+	_, serviceErr = genericService.buildTextSearch(listCtx, &d)
+	Expect(serviceErr).To(HaveOccurred())
+	Expect(serviceErr.Code).To(Equal(errors.ErrorBadRequest))
+	Expect(serviceErr.Error()).To(Equal("rh-trex-21: Dinosaur can't be searched by text"))
+
+	// the queries ordering by relevance are embedded in the SQL, quoted
+	Expect(db.TextSearchRank("dinosaurs.search_vector", "rex' or 1=1 --")).To(Equal("ts_rank(dinosaurs.search_vector, websearch_to_tsquery('english', 'rex'' or 1=1 --')) desc"))
+
+	// the tokens are only valid for the query they were issued for
+	Expect(queryDigest("Dinosaur", &ListArguments{Query: "rex"})).ToNot(Equal(queryDigest("Dinosaur", &ListArguments{Query: "stego"})))
+}
+
+func TestIncludeRelations(t *testing.T) {
+	RegisterTestingT(t)
+
+	SearchSchemas["Nest"] = &db.SearchSchema{Table: "nests", Fields: map[string]db.SearchField{"location": {Type: db.SearchString}}}
+	SearchSchemas["Egg"] = &db.SearchSchema{Table: "eggs", Fields: map[string]db.SearchField{"size": {Type: db.SearchNumber}}}
+	Relations["Nest"] = map[string]Relation{"egg": {Association: "Egg", Kind: "Egg"}}
+	defer func() {
+		delete(SearchSchemas, "Nest")
+		delete(SearchSchemas, "Egg")
+		delete(Relations, "Nest")
+	}()
+
+	// the includes are the API names of the relations, preloading their associations
+	associations, serviceErr := includedAssociations("Nest", []string{"egg"})
+	Expect(serviceErr).ToNot(HaveOccurred())
+	Expect(associations).To(Equal([]string{"Egg"}))
+	_, serviceErr = includedAssociations("Nest", []string{"dinosaur"})
+	Expect(serviceErr.Code).To(Equal(errors.ErrorBadRequest))
+	Expect(serviceErr.Error()).To(Equal("rh-trex-21: dinosaur is not a relation of Nest, the relations are egg"))
+	_, serviceErr = includedAssociations("Egg", []string{"nest"})
+	Expect(serviceErr.Error()).To(Equal("rh-trex-21: Egg has no relations to include"))
+
+	// the searches of a kind can use the fields of its related kinds, without changing their schemas
+	schema := searchSchema("Nest")
+	Expect(schema.Related).To(HaveKeyWithValue("eggs", SearchSchemas["Egg"]))
+	Expect(SearchSchemas["Nest"].Related).To(BeNil())
+	Expect(searchSchema("Egg")).To(BeIdenticalTo(SearchSchemas["Egg"]))
+}
+
+func TestQueryGuard(t *testing.T) {
+	RegisterTestingT(t)
+	var dbFactory db.SessionFactory = dbmocks.NewMockSessionFactory()
+	defer dbFactory.Close()
+
+	g := dao.NewGenericDao(&dbFactory)
+	genericService := sqlGenericService{genericDao: g, queryLimits: &config.QueryLimitsConfig{MaxSearchNodes: 9, MaxInListLength: 2}}
+
+	// the nodes of the searches are their comparisons, fields, values, in lists and the and/or/not joining them
+	tslTree, err := tsl.ParseTSL("species = 'rex' and species in ('stego', 'ptero')")
+	Expect(err).ToNot(HaveOccurred())
+	nodes, longest := searchNodes(tslTree)
+	Expect(nodes).To(Equal(9))
+	Expect(longest).To(Equal(2))
+
+	// the searches exceeding a limit are rejected, explaining which
+	tests := []struct {
+		search string
+		error  string
+	}{
+		{search: "species = 'rex' and species in ('stego', 'ptero')"},
+		{search: "species = 'rex' and species = 'stego' and species = 'ptero'", error: "rh-trex-21: The search has 11 nodes, more than the 9 allowed"},
+		{search: "species in ('rex', 'stego', 'ptero')", error: "rh-trex-21: The search has an in list of 3 values, more than the 2 allowed"},
+	}
+	for _, test := range tests {
+		var list []api.Dinosaur
+		listCtx, model, serviceErr := genericService.newListContext(context.Background(), "", &ListArguments{Search: test.search}, &list)
+		Expect(serviceErr).ToNot(HaveOccurred())
+		listCtx.set = map[string]bool{}
+		d := g.GetInstanceDao(context.Background(), model)
+		_, serviceErr = genericService.buildSearch(listCtx, &d)
+		if test.error == "" {
+			Expect(serviceErr).ToNot(HaveOccurred(), test.search)
+			continue
+		}
+		Expect(serviceErr).To(HaveOccurred(), test.search)
+		Expect(serviceErr.Code).To(Equal(errors.ErrorBadRequest))
+		Expect(serviceErr.Error()).To(Equal(test.error))
+	}
+
+	// the services without limits accept any search
+	genericService.queryLimits = nil
+	var list []api.Dinosaur
+	listCtx, model, serviceErr := genericService.newListContext(context.Background(), "", &ListArguments{Search: tests[1].search}, &list)
+	Expect(serviceErr).ToNot(HaveOccurred())
+	d := g.GetInstanceDao(context.Background(), model)
+	_, serviceErr = genericService.buildSearch(listCtx, &d)
+	Expect(serviceErr).ToNot(HaveOccurred())
+}
+
+// fossilSite, museum and fossil are models of the tests only, a fossil relates to its site and to its museum
+type fossilSite struct {
+	api.Meta
+	Location string
+}
+
+type museum struct {
+	api.Meta
+	Name string
+}
+
+type fossil struct {
+	api.Meta
+	FossilSiteID string
+	FossilSite   *fossilSite `gorm:"foreignKey:FossilSiteID"`
+	MuseumID     string
+	Museum       *museum `gorm:"foreignKey:MuseumID"`
+}
+
+func TestQueryGuardJoinsAndPlan(t *testing.T) {
+	RegisterTestingT(t)
+	var dbFactory db.SessionFactory = dbmocks.NewMockSessionFactory()
+	defer dbFactory.Close()
+
+	g := dao.NewGenericDao(&dbFactory)
+	genericService := sqlGenericService{genericDao: g, queryLimits: &config.QueryLimitsConfig{MaxJoins: 1}}
+
+	// the searches joining more related kinds than allowed are rejected, and counted
+	rejected := testutil.ToFloat64(rejectedQueryCountMetric.WithLabelValues("fossil", joinsLimit))
+	for search, error := range map[string]string{
+		"fossil_site.location = 'badlands'":                        "",
+		"fossil_site.location = 'badlands' and museum.name = 'mx'": "rh-trex-21: The search joins 2 related kinds, more than the 1 allowed",
+	} {
+		var list []fossil
+		listCtx, model, serviceErr := genericService.newListContext(context.Background(), "", &ListArguments{Search: search}, &list)
+		Expect(serviceErr).ToNot(HaveOccurred())
+		listCtx.set = map[string]bool{}
+		d := g.GetInstanceDao(context.Background(), model)
+		_, serviceErr = genericService.buildSearch(listCtx, &d)
+		if error == "" {
+			Expect(serviceErr).ToNot(HaveOccurred(), search)
+			continue
+		}
+		Expect(serviceErr).To(HaveOccurred(), search)
+		Expect(serviceErr.Error()).To(Equal(error))
+	}
+	Expect(testutil.ToFloat64(rejectedQueryCountMetric.WithLabelValues("fossil", joinsLimit))).To(Equal(rejected + 1))
+
+	// the plans are explained for the page the list loads, the exports for all the resources
+	genericDao := mocks.NewGenericDao()
+	var explained [][2]int
+	genericDao.PlanCost = func(offset int, limit int) float64 {
+		explained = append(explained, [2]int{offset, limit})
+		if limit == 0 {
+			return 1e6
+		}
+		return float64(limit)
+	}
+	genericService = sqlGenericService{genericDao: genericDao, queryLimits: &config.QueryLimitsConfig{MaxPlanCost: 100}}
+	rejected = testutil.ToFloat64(rejectedQueryCountMetric.WithLabelValues("Dinosaur", planCostLimit))
+
+	var list []api.Dinosaur
+	_, serviceErr := genericService.List(context.Background(), "", &ListArguments{Page: 3, Size: 10}, &list)
+	Expect(serviceErr).ToNot(HaveOccurred())
+	_, serviceErr = genericService.List(context.Background(), "", &ListArguments{Page: 1, Size: 500}, &list)
+	Expect(serviceErr).To(HaveOccurred())
+	Expect(serviceErr.Code).To(Equal(errors.ErrorBadRequest))
+	Expect(serviceErr.Error()).To(Equal("rh-trex-21: The list is too expensive, its query costs 500, more than the 100 allowed, narrow its search"))
+	serviceErr = genericService.Export(context.Background(), "", &ListArguments{}, &list, func(resource interface{}) error { return nil })
+	Expect(serviceErr).To(HaveOccurred())
+	Expect(explained).To(Equal([][2]int{{20, 10}, {0, 500}, {0, 0}}))
+	Expect(testutil.ToFloat64(rejectedQueryCountMetric.WithLabelValues("Dinosaur", planCostLimit))).To(Equal(rejected + 2))
+}
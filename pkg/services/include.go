@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/openshift-online/rh-trex/pkg/db"
+	"github.com/openshift-online/rh-trex/pkg/errors"
+)
+
+// Relation is a kind related to another one, which the lists and the gets of the other one include when asked to
+type Relation struct {
+	// Association is the field of the model holding the related resources, preloaded by gorm
+	Association string
+	// Kind is the related kind, whose search schema the searches of the related resources use
+	Kind string
+}
+
+// Include loads the relations of the resource the get includes, reloading it with them. resource must be a pointer
+// to a database resource object which has been loaded.
+func (s *sqlGenericService) Include(ctx context.Context, include []string, resource interface{}) *errors.ServiceError {
+	if len(include) == 0 {
+		return nil
+	}
+	resourceType := reflect.TypeOf(resource).Elem().Name()
+	associations, err := includedAssociations(resourceType, include)
+	if err != nil {
+		return err
+	}
+
+	d := s.genericDao.GetInstanceDao(ctx, resource)
+	for _, association := range associations {
+		d.Preload(association)
+	}
+	if err := d.Take(resource); err != nil {
+		return handleGetError(resourceType, "id", reflect.ValueOf(resource).Elem().FieldByName("ID").Interface(), err)
+	}
+	return nil
+}
+
+// includedAssociations returns the associations of the relations to include, which must be relations of the kind
+func includedAssociations(resourceType string, include []string) ([]string, *errors.ServiceError) {
+	relations := Relations[resourceType]
+	var associations []string
+	for _, name := range include {
+		relation, ok := relations[name]
+		if ok {
+			associations = append(associations, relation.Association)
+			continue
+		}
+		if len(relations) == 0 {
+			return nil, errors.BadRequest("%s has no relations to include", resourceType)
+		}
+		names := make([]string, 0, len(relations))
+		for relationName := range relations {
+			names = append(names, relationName)
+		}
+		sort.Strings(names)
+		return nil, errors.BadRequest("%s is not a relation of %s, the relations are %s", name, resourceType, strings.Join(names, ", "))
+	}
+	return associations, nil
+}
+
+// searchSchema returns the search schema of the kind with the ones of its related kinds, for the searches of the
+// related resources, nil when the kind has none
+func searchSchema(resourceType string) *db.SearchSchema {
+	schema := SearchSchemas[resourceType]
+	if schema == nil || len(Relations[resourceType]) == 0 {
+		return schema
+	}
+	withRelated := *schema
+	withRelated.Related = map[string]*db.SearchSchema{}
+	for _, relation := range Relations[resourceType] {
+		if related := SearchSchemas[relation.Kind]; related != nil {
+			withRelated.Related[related.Table] = related
+		}
+	}
+	return &withRelated
+}
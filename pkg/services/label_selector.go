@@ -0,0 +1,143 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+)
+
+type LabelOperator string
+
+const (
+	LabelEquals       LabelOperator = "="
+	LabelNotEquals    LabelOperator = "!="
+	LabelIn           LabelOperator = "in"
+	LabelNotIn        LabelOperator = "notin"
+	LabelExists       LabelOperator = "exists"
+	LabelDoesNotExist LabelOperator = "!"
+)
+
+// LabelRequirement is a requirement of a label selector, e.g. `tier in (gold, silver)`
+type LabelRequirement struct {
+	Key      string
+	Operator LabelOperator
+	Values   []string
+}
+
+var labelSetPattern = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// ParseLabelSelector parses a Kubernetes style label selector: comma separated requirements that must all be met,
+// `key=value` (or `key==value`), `key!=value`, `key in (v1,v2)`, `key notin (v1,v2)`, `key` and `!key`.
+func ParseLabelSelector(selector string) ([]LabelRequirement, error) {
+	var requirements []LabelRequirement
+	for _, term := range splitLabelSelector(selector) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("empty requirement in label selector '%s'", selector)
+		}
+
+		var requirement LabelRequirement
+		switch {
+		case labelSetPattern.MatchString(term):
+			match := labelSetPattern.FindStringSubmatch(term)
+			if strings.TrimSpace(match[3]) == "" {
+				return nil, fmt.Errorf("empty set of values in label selector requirement '%s'", term)
+			}
+			requirement = LabelRequirement{Key: match[1], Operator: LabelOperator(match[2])}
+			for _, value := range strings.Split(match[3], ",") {
+				requirement.Values = append(requirement.Values, strings.TrimSpace(value))
+			}
+		case strings.HasPrefix(term, "!") && !strings.Contains(term, "="):
+			requirement = LabelRequirement{Key: strings.TrimSpace(term[1:]), Operator: LabelDoesNotExist}
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			requirement = LabelRequirement{Key: strings.TrimSpace(parts[0]), Operator: LabelNotEquals, Values: []string{strings.TrimSpace(parts[1])}}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(strings.Replace(term, "==", "=", 1), "=", 2)
+			requirement = LabelRequirement{Key: strings.TrimSpace(parts[0]), Operator: LabelEquals, Values: []string{strings.TrimSpace(parts[1])}}
+		default:
+			requirement = LabelRequirement{Key: term, Operator: LabelExists}
+		}
+
+		if err := api.ValidateLabelKey(requirement.Key); err != nil {
+			return nil, err
+		}
+		for _, value := range requirement.Values {
+			if err := api.ValidateLabelValue(value); err != nil {
+				return nil, err
+			}
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements, nil
+}
+
+// splitLabelSelector splits the selector on the commas that are not within the values of a set
+func splitLabelSelector(selector string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+// ToSql translates the requirement into a predicate on the JSONB labels column. Equalities are containments,
+// which the GIN index of the column serves. As in Kubernetes, the negations match the resources without the label.
+func (r LabelRequirement) ToSql(column string) (string, []any, error) {
+	switch r.Operator {
+	case LabelEquals, LabelNotEquals:
+		document, err := json.Marshal(map[string]string{r.Key: r.Values[0]})
+		if err != nil {
+			return "", nil, err
+		}
+		sql := fmt.Sprintf("%s @> ?::jsonb", column)
+		if r.Operator == LabelNotEquals {
+			sql = fmt.Sprintf("NOT (%s)", sql)
+		}
+		return sql, []any{string(document)}, nil
+	case LabelIn, LabelNotIn:
+		values := []any{r.Key}
+		for _, value := range r.Values {
+			values = append(values, value)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(r.Values)), ",")
+		if r.Operator == LabelIn {
+			return fmt.Sprintf("%s->>? IN (%s)", column, placeholders), values, nil
+		}
+		return fmt.Sprintf("(%s->>? IS NULL OR %s->>? NOT IN (%s))", column, column, placeholders), append([]any{r.Key}, values...), nil
+	case LabelExists:
+		return fmt.Sprintf("%s->>? IS NOT NULL", column), []any{r.Key}, nil
+	case LabelDoesNotExist:
+		return fmt.Sprintf("%s->>? IS NULL", column), []any{r.Key}, nil
+	}
+	return "", nil, fmt.Errorf("unsupported label operator '%s'", r.Operator)
+}
+
+// labelSelectorSql translates the requirements into the predicate on the JSONB labels column they must all meet
+func labelSelectorSql(column string, requirements []LabelRequirement) (string, []any, error) {
+	predicates := squirrel.And{}
+	for _, requirement := range requirements {
+		sql, values, err := requirement.ToSql(column)
+		if err != nil {
+			return "", nil, err
+		}
+		predicates = append(predicates, squirrel.Expr(sql, values...))
+	}
+	return predicates.ToSql()
+}
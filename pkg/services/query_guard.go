@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/yaacov/tree-search-language/pkg/tsl"
+
+	"github.com/openshift-online/rh-trex/pkg/config"
+	"github.com/openshift-online/rh-trex/pkg/dao"
+	"github.com/openshift-online/rh-trex/pkg/errors"
+)
+
+// The limits of config.QueryLimitsConfig, labeling the rejected lists:
+const (
+	searchNodesLimit  = "search_nodes"
+	joinsLimit        = "joins"
+	inListLengthLimit = "in_list_length"
+	planCostLimit     = "plan_cost"
+)
+
+// Description of the rejected lists count metric:
+var rejectedQueryCountMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Subsystem: "list_query",
+		Name:      "rejected_count",
+		Help:      "Number of lists rejected for exceeding a query limit.",
+	},
+	[]string{"kind", "limit"},
+)
+
+// RegisterQueryGuardMetrics registers the metrics of the lists rejected by the query limits.
+// Registering again, e.g. when the metrics server is restarted, keeps the first collector.
+func RegisterQueryGuardMetrics() error {
+	err := prometheus.Register(rejectedQueryCountMetric)
+	if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		return nil
+	}
+	return err
+}
+
+// limits returns the query limits of the service, none when it has no config
+func (s *sqlGenericService) limits() config.QueryLimitsConfig {
+	if s.queryLimits == nil {
+		return config.QueryLimitsConfig{}
+	}
+	return *s.queryLimits
+}
+
+// guardSearch rejects the searches with too many nodes or too long in lists
+func (s *sqlGenericService) guardSearch(listCtx *listContext, tslTree tsl.Node) *errors.ServiceError {
+	limits := s.limits()
+	nodes, longest := searchNodes(tslTree)
+	if limits.MaxSearchNodes > 0 && nodes > limits.MaxSearchNodes {
+		return s.rejectQuery(listCtx, searchNodesLimit, "The search has %d nodes, more than the %d allowed", nodes, limits.MaxSearchNodes)
+	}
+	if limits.MaxInListLength > 0 && longest > limits.MaxInListLength {
+		return s.rejectQuery(listCtx, inListLengthLimit, "The search has an in list of %d values, more than the %d allowed", longest, limits.MaxInListLength)
+	}
+	return nil
+}
+
+// guardJoins rejects the searches joining too many related kinds
+func (s *sqlGenericService) guardJoins(listCtx *listContext) *errors.ServiceError {
+	limits := s.limits()
+	if joins := len(listCtx.joins); limits.MaxJoins > 0 && joins > limits.MaxJoins {
+		return s.rejectQuery(listCtx, joinsLimit, "The search joins %d related kinds, more than the %d allowed", joins, limits.MaxJoins)
+	}
+	return nil
+}
+
+// guardPlan rejects the lists whose query plan costs too much, explaining the query of the page the list is about to
+// load, of all the resources when limit is 0
+func (s *sqlGenericService) guardPlan(listCtx *listContext, d *dao.GenericDao, offset int, limit int) *errors.ServiceError {
+	limits := s.limits()
+	if limits.MaxPlanCost <= 0 {
+		return nil
+	}
+	var cost float64
+	if err := (*d).ExplainCost(listCtx.resourceList, offset, limit, &cost); err != nil {
+		return errors.GeneralError("Unable to explain the list of %s: %s", listCtx.resourceType, err)
+	}
+	if cost > limits.MaxPlanCost {
+		return s.rejectQuery(listCtx, planCostLimit, "The list is too expensive, its query costs %.0f, more than the %.0f allowed, narrow its search", cost, limits.MaxPlanCost)
+	}
+	return nil
+}
+
+// rejectQuery logs and counts the list rejected for exceeding the limit, returning the error explaining it
+func (s *sqlGenericService) rejectQuery(listCtx *listContext, limit string, reason string, values ...interface{}) *errors.ServiceError {
+	reason = fmt.Sprintf(reason, values...)
+	(*listCtx.ulog).Infof("Rejected the list of %s exceeding the %s limit: %s", listCtx.resourceType, limit, reason)
+	rejectedQueryCountMetric.With(prometheus.Labels{"kind": listCtx.resourceType, "limit": limit}).Inc()
+	return errors.BadRequest("%s", reason)
+}
+
+// searchNodes returns the number of nodes of the search and the length of its longest in list
+func searchNodes(n tsl.Node) (nodes int, longest int) {
+	nodes = 1
+	if (n.Func == tsl.InOp || n.Func == tsl.NotInOp) && n.Right != nil {
+		values, _ := n.Right.([]tsl.Node)
+		if r, ok := n.Right.(tsl.Node); ok && r.Func == tsl.ArrayOp {
+			values, _ = r.Right.([]tsl.Node)
+		}
+		longest = len(values)
+	}
+	var children []tsl.Node
+	for _, side := range []interface{}{n.Left, n.Right} {
+		switch child := side.(type) {
+		case tsl.Node:
+			children = append(children, child)
+		case []tsl.Node:
+			children = append(children, child...)
+		}
+	}
+	for _, child := range children {
+		childNodes, childLongest := searchNodes(child)
+		nodes += childNodes
+		longest = max(longest, childLongest)
+	}
+	return nodes, longest
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/yaacov/tree-search-language/pkg/tsl"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/auth"
+	"github.com/openshift-online/rh-trex/pkg/dao"
+	"github.com/openshift-online/rh-trex/pkg/db"
+	"github.com/openshift-online/rh-trex/pkg/errors"
+)
+
+// SavedSearchService manages the saved searches of the caller, who owns the ones it creates and only sees those
+type SavedSearchService interface {
+	Get(ctx context.Context, id string) (*api.SavedSearch, *errors.ServiceError)
+	Create(ctx context.Context, savedSearch *api.SavedSearch) (*api.SavedSearch, *errors.ServiceError)
+	Replace(ctx context.Context, savedSearch *api.SavedSearch) (*api.SavedSearch, *errors.ServiceError)
+	Delete(ctx context.Context, id string) *errors.ServiceError
+	All(ctx context.Context) (api.SavedSearchList, *errors.ServiceError)
+}
+
+func NewSavedSearchService(savedSearchDao dao.SavedSearchDao) SavedSearchService {
+	return &sqlSavedSearchService{
+		savedSearchDao: savedSearchDao,
+	}
+}
+
+var _ SavedSearchService = &sqlSavedSearchService{}
+
+type sqlSavedSearchService struct {
+	savedSearchDao dao.SavedSearchDao
+}
+
+func (s *sqlSavedSearchService) Get(ctx context.Context, id string) (*api.SavedSearch, *errors.ServiceError) {
+	savedSearch, err := s.savedSearchDao.Get(ctx, auth.GetUsernameFromContext(ctx), id)
+	if err != nil {
+		return nil, handleGetError("SavedSearch", "id", id, err)
+	}
+	return savedSearch, nil
+}
+
+func (s *sqlSavedSearchService) Create(ctx context.Context, savedSearch *api.SavedSearch) (*api.SavedSearch, *errors.ServiceError) {
+	if err := validateSavedSearch(savedSearch); err != nil {
+		return nil, err
+	}
+	savedSearch.Owner = auth.GetUsernameFromContext(ctx)
+	savedSearch, err := s.savedSearchDao.Create(ctx, savedSearch)
+	if err != nil {
+		return nil, handleCreateError("SavedSearch", err)
+	}
+	AuditChange(ctx, "SavedSearch", savedSearch.ID, nil, savedSearch)
+	return savedSearch, nil
+}
+
+// Replace saves the saved search of the caller, all its fields are replaced
+func (s *sqlSavedSearchService) Replace(ctx context.Context, savedSearch *api.SavedSearch) (*api.SavedSearch, *errors.ServiceError) {
+	if err := validateSavedSearch(savedSearch); err != nil {
+		return nil, err
+	}
+	found, err := s.savedSearchDao.Get(ctx, auth.GetUsernameFromContext(ctx), savedSearch.ID)
+	if err != nil {
+		return nil, handleGetError("SavedSearch", "id", savedSearch.ID, err)
+	}
+
+	before := *found
+	found.Name = savedSearch.Name
+	found.ResourceKind = savedSearch.ResourceKind
+	found.Search = savedSearch.Search
+	found.OrderBy = savedSearch.OrderBy
+	found.Fields = savedSearch.Fields
+	updated, err := s.savedSearchDao.Replace(ctx, found)
+	if err != nil {
+		return nil, handleUpdateError("SavedSearch", err)
+	}
+	AuditChange(ctx, "SavedSearch", updated.ID, &before, updated)
+	return updated, nil
+}
+
+func (s *sqlSavedSearchService) Delete(ctx context.Context, id string) *errors.ServiceError {
+	owner := auth.GetUsernameFromContext(ctx)
+	// only read for the audit log, deleting a missing saved search is not an error
+	before, _ := s.savedSearchDao.Get(ctx, owner, id)
+
+	if err := s.savedSearchDao.Delete(ctx, owner, id); err != nil {
+		return handleDeleteError("SavedSearch", err)
+	}
+	AuditChange(ctx, "SavedSearch", id, before, nil)
+	return nil
+}
+
+// All returns the saved searches of the caller
+func (s *sqlSavedSearchService) All(ctx context.Context) (api.SavedSearchList, *errors.ServiceError) {
+	savedSearches, err := s.savedSearchDao.FindByOwner(ctx, auth.GetUsernameFromContext(ctx))
+	if err != nil {
+		return nil, errors.GeneralError("Unable to get all saved searches: %s", err)
+	}
+	return savedSearches, nil
+}
+
+// validateSavedSearch checks that the saved search is a query of a searchable kind which parses. Its fields are
+// checked against the search schema of the kind when a list uses it, see GenericService.ExpandView.
+func validateSavedSearch(savedSearch *api.SavedSearch) *errors.ServiceError {
+	if SearchSchemas[savedSearch.ResourceKind] == nil {
+		kinds := make([]string, 0, len(SearchSchemas))
+		for kind := range SearchSchemas {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		return errors.Validation("%s is not a kind with saved searches, the kinds are %s", savedSearch.ResourceKind, strings.Join(kinds, ", "))
+	}
+	if search := strings.Trim(savedSearch.Search, " "); search != "" {
+		if _, err := tsl.ParseTSL(search); err != nil {
+			return errors.Validation("Failed to parse search query: %s", search)
+		}
+	}
+	disallowedFields := SearchDisallowedFields[savedSearch.ResourceKind]
+	if disallowedFields == nil {
+		disallowedFields = allFieldsAllowed
+	}
+	if _, err := db.ArgsToOrderBy(splitList(savedSearch.OrderBy), disallowedFields, searchSchema(savedSearch.ResourceKind)); err != nil {
+		return errors.Validation("%s", err.Reason)
+	}
+	for _, field := range splitList(savedSearch.Fields) {
+		if presentedFields[field] {
+			continue
+		}
+		if err := SearchSchemas[savedSearch.ResourceKind].CheckField(field); err != nil {
+			return errors.Validation("%s", err.Reason)
+		}
+	}
+	return nil
+}
+
+// presentedFields are the fields every resource is presented with, which the lists can project without being in the schema
+var presentedFields = map[string]bool{"id": true, "kind": true, "href": true}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/yaacov/tree-search-language/pkg/tsl"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/config"
+	"github.com/openshift-online/rh-trex/pkg/dao"
+	"github.com/openshift-online/rh-trex/pkg/db"
+	dbmocks "github.com/openshift-online/rh-trex/pkg/db/mocks"
+)
+
+func TestValidateSavedSearch(t *testing.T) {
+	RegisterTestingT(t)
+
+	SearchSchemas["Nest"] = &db.SearchSchema{Table: "nests", Fields: map[string]db.SearchField{"location": {Type: db.SearchString}}}
+	defer delete(SearchSchemas, "Nest")
+
+	Expect(validateSavedSearch(&api.SavedSearch{ResourceKind: "Nest", Search: "location = 'cave'", OrderBy: "location desc", Fields: "id,location,kind"})).To(BeNil())
+
+	tests := []struct {
+		savedSearch api.SavedSearch
+		error       string
+	}{
+		{savedSearch: api.SavedSearch{ResourceKind: "Egg"}, error: "rh-trex-8: Egg is not a kind with saved searches, the kinds are "},
+		{savedSearch: api.SavedSearch{ResourceKind: "Nest", Search: "location = "}, error: "rh-trex-8: Failed to parse search query: location ="},
+		{savedSearch: api.SavedSearch{ResourceKind: "Nest", OrderBy: "size"}, error: "rh-trex-8: size is not a valid field name, the fields are location"},
+		{savedSearch: api.SavedSearch{ResourceKind: "Nest", Fields: "location,size"}, error: "rh-trex-8: size is not a valid field name, the fields are location"},
+	}
+	for _, test := range tests {
+		err := validateSavedSearch(&test.savedSearch)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(HavePrefix(test.error))
+	}
+}
+
+func TestApplyView(t *testing.T) {
+	RegisterTestingT(t)
+
+	view := viewArguments(&api.SavedSearch{Search: "species like 'Stego%'", OrderBy: "created_at desc, species", Fields: "species"})
+	Expect(view.OrderBy).To(Equal([]string{"created_at desc", "species"}))
+	Expect(view.Fields).To(Equal([]string{"species", "id"}))
+
+	// the view gives the arguments the list leaves out
+	args := &ListArguments{}
+	applyView(args, view)
+	Expect(args.Search).To(Equal("species like 'Stego%'"))
+	Expect(args.OrderBy).To(Equal([]string{"created_at desc", "species"}))
+	Expect(args.Fields).To(Equal([]string{"species", "id"}))
+
+	// the search of the list narrows the one of the view, its ordering and fields replace the ones of the view
+	args = &ListArguments{Search: "created_at > '2024-05-01'", OrderBy: []string{"species"}, Fields: []string{"id", "created_at"}}
+	applyView(args, view)
+	Expect(args.Search).To(Equal("(species like 'Stego%') and (created_at > '2024-05-01')"))
+	_, err := tsl.ParseTSL(args.Search)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(args.OrderBy).To(Equal([]string{"species"}))
+	Expect(args.Fields).To(Equal([]string{"id", "created_at"}))
+}
+
+func TestCheckView(t *testing.T) {
+	RegisterTestingT(t)
+	var dbFactory db.SessionFactory = dbmocks.NewMockSessionFactory()
+	defer dbFactory.Close()
+
+	genericService := sqlGenericService{genericDao: dao.NewGenericDao(&dbFactory), queryLimits: &config.QueryLimitsConfig{MaxSearchNodes: 1}}
+
+	// the views are checked without the query limits, which the lists using them enforce
+	var list []api.Dinosaur
+	Expect(genericService.checkView(context.Background(), &ListArguments{Search: "species = 'rex'"}, &list)).To(BeNil())
+	err := genericService.checkView(context.Background(), &ListArguments{Search: "species = "}, &list)
+	Expect(err).ToNot(BeNil())
+	Expect(err.Error()).To(Equal("rh-trex-21: Failed to parse search query: species ="))
+}
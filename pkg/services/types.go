@@ -9,14 +9,36 @@ import (
 // ListArguments are arguments relevant for listing objects.
 // This struct is common to all service List funcs in this package
 type ListArguments struct {
-	Page     int
-	Size     int64
+	Page int
+	Size int64
+	// Preloads are the relations the list includes, see Relations
 	Preloads []string
 	Search   string
 	OrderBy  []string
 	Fields   []string
+	// Query is the full-text query of the resources, see FullTextSearchColumns
+	Query string
+	// LabelSelector selects the resources by their labels, see ParseLabelSelector
+	LabelSelector string
+	// Deleted lists the soft-deleted resources instead of the live ones, reserved to administrators (see auth.IsAdmin)
+	Deleted bool
+	// Continue is the token of a previous list to continue it after its last resource, see PagingMeta.Continue
+	Continue string
+	// Total is how the resources matching the list are counted, TotalExact when empty
+	Total string
+	// View is the name of a saved search of the caller the list uses, see GenericService.ExpandView
+	View string
 }
 
+const (
+	// TotalExact counts the resources
+	TotalExact = "exact"
+	// TotalEstimate estimates the number of resources from the planner statistics, see dao.GenericDao.EstimateCount
+	TotalEstimate = "estimate"
+	// TotalNone doesn't count the resources, the lists report a total of -1
+	TotalNone = "none"
+)
+
 // ~65500 is the maximum number of parameters that can be provided to a postgres WHERE IN clause
 // Use it as a sane max
 const MaxListSize = 65500
@@ -42,26 +64,98 @@ func NewListArguments(params url.Values) *ListArguments {
 	if v := strings.Trim(params.Get("search"), " "); v != "" {
 		listArgs.Search = v
 	}
+	if v := strings.Trim(params.Get("q"), " "); v != "" {
+		listArgs.Query = v
+	}
+	listArgs.Preloads = NewIncludeArguments(params)
+	if v := strings.Trim(params.Get("labelSelector"), " "); v != "" {
+		listArgs.LabelSelector = v
+	}
+	if v := strings.Trim(params.Get("continue"), " "); v != "" {
+		listArgs.Continue = v
+	}
+	if v := strings.Trim(params.Get("total"), " "); v != "" {
+		listArgs.Total = v
+	}
+	if v := strings.Trim(params.Get("deleted"), " "); v != "" {
+		listArgs.Deleted, _ = strconv.ParseBool(v)
+	}
 	if v := strings.Trim(params.Get("orderBy"), " "); v != "" {
 		listArgs.OrderBy = strings.Split(v, ",")
 	}
-	if v := strings.Trim(params.Get("fields"), " "); v != "" {
-		fields := strings.Split(v, ",")
-		idNotPresent := true
-		for i := 0; i < len(fields); i++ {
-			field := strings.Trim(fields[i], " ")
-			if field == "" { // skip leading/trailing commas and spaces
-				continue
-			}
-			if field == "id" {
-				idNotPresent = false
-			}
-			listArgs.Fields = append(listArgs.Fields, field)
-		}
-		if idNotPresent {
-			listArgs.Fields = append(listArgs.Fields, "id")
-		}
+	if v := strings.Trim(params.Get("view"), " "); v != "" {
+		listArgs.View = v
 	}
+	listArgs.Fields = fieldsList(params.Get("fields"))
 
 	return listArgs
 }
+
+// AggregateArguments are arguments relevant for aggregating objects, see GenericService.Aggregate
+type AggregateArguments struct {
+	Search        string
+	LabelSelector string
+	Deleted       bool
+	// GroupBy is the columns the resources are grouped by, a single group of them all when empty
+	GroupBy []string
+	// Aggregates is what is computed for each group: AggregateCount, min(<column>) or max(<column>)
+	Aggregates []string
+}
+
+// AggregateCount counts the resources of each group
+const AggregateCount = "count"
+
+// NewAggregateArguments Create AggregateArguments from url query parameters, counting the resources by default
+func NewAggregateArguments(params url.Values) *AggregateArguments {
+	aggregateArgs := &AggregateArguments{
+		Aggregates: []string{AggregateCount},
+	}
+	if v := strings.Trim(params.Get("search"), " "); v != "" {
+		aggregateArgs.Search = v
+	}
+	if v := strings.Trim(params.Get("labelSelector"), " "); v != "" {
+		aggregateArgs.LabelSelector = v
+	}
+	if v := strings.Trim(params.Get("deleted"), " "); v != "" {
+		aggregateArgs.Deleted, _ = strconv.ParseBool(v)
+	}
+	if v := strings.Trim(params.Get("groupBy"), " "); v != "" {
+		aggregateArgs.GroupBy = splitList(v)
+	}
+	if v := strings.Trim(params.Get("aggregates"), " "); v != "" {
+		aggregateArgs.Aggregates = splitList(v)
+	}
+	return aggregateArgs
+}
+
+// NewIncludeArguments returns the relations the include parameter asks the lists and the gets to include,
+// a comma separated list of their names
+func NewIncludeArguments(params url.Values) []string {
+	return splitList(params.Get("include"))
+}
+
+// fieldsList splits the comma separated fields of a list, adding the id the lists are always presented with,
+// nil when there are none
+func fieldsList(v string) []string {
+	fields := splitList(v)
+	if len(fields) == 0 {
+		return nil
+	}
+	for _, field := range fields {
+		if field == "id" {
+			return fields
+		}
+	}
+	return append(fields, "id")
+}
+
+// splitList splits a comma separated list, skipping the empty items
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.Trim(item, " "); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -2,10 +2,10 @@ package services
 
 import (
 	e "errors"
-	"strings"
 
 	"gorm.io/gorm"
 
+	"github.com/openshift-online/rh-trex/pkg/db"
 	"github.com/openshift-online/rh-trex/pkg/errors"
 )
 
@@ -29,23 +29,71 @@ func handleGetError(resourceType, field string, value interface{}, err error) *e
 	if e.Is(err, gorm.ErrRecordNotFound) {
 		return errors.NotFound("%s with %s='%v' not found", resourceType, field, value)
 	}
+	if db.IsRetryable(err) {
+		return errors.DatabaseTransient("Unable to find %s with %s='%v': %s", resourceType, field, value, err)
+	}
 	return errors.GeneralError("Unable to find %s with %s='%v': %s", resourceType, field, value, err)
 }
 
 func handleCreateError(resourceType string, err error) *errors.ServiceError {
-	if strings.Contains(err.Error(), "violates unique constraint") {
-		return errors.Conflict("This %s already exists", resourceType)
+	if db.IsRetryable(err) {
+		return errors.DatabaseTransient("Unable to create %s: %s", resourceType, err.Error())
+	}
+	if svcErr := handleConstraintError(resourceType, err); svcErr != nil {
+		return svcErr
 	}
 	return errors.GeneralError("Unable to create %s: %s", resourceType, err.Error())
 }
 
 func handleUpdateError(resourceType string, err error) *errors.ServiceError {
-	if strings.Contains(err.Error(), "violates unique constraint") {
-		return errors.Conflict("Changes to %s conflict with existing records", resourceType)
+	if db.IsRetryable(err) {
+		return errors.DatabaseTransient("Unable to update %s: %s", resourceType, err.Error())
+	}
+	if svcErr := handleConstraintError(resourceType, err); svcErr != nil {
+		return svcErr
 	}
 	return errors.GeneralError("Unable to update %s: %s", resourceType, err.Error())
 }
 
 func handleDeleteError(resourceType string, err error) *errors.ServiceError {
+	if db.IsRetryable(err) {
+		return errors.DatabaseTransient("Unable to delete %s: %s", resourceType, err.Error())
+	}
+	if svcErr := handleConstraintError(resourceType, err); svcErr != nil {
+		return svcErr
+	}
 	return errors.GeneralError("Unable to delete %s: %s", resourceType, err.Error())
 }
+
+// handleConstraintError maps integrity constraint violations to service errors naming the offending fields,
+// it returns nil for any other error. Values are left out of the reasons, they may contain personally identifiable information.
+func handleConstraintError(resourceType string, err error) *errors.ServiceError {
+	var constraintErr *db.ConstraintError
+	if !e.As(db.TranslateError(err), &constraintErr) {
+		return nil
+	}
+	fields := constraintErr.FieldNames()
+	switch constraintErr.Kind {
+	case db.UniqueConstraint:
+		return errors.Conflict("%s with the same %s already exists", resourceType, fields)
+	case db.ExclusionConstraint:
+		return errors.Conflict("%s conflicts with an existing %s on %s", resourceType, resourceType, fields)
+	case db.ForeignKeyConstraint:
+		if constraintErr.Referenced {
+			return errors.Conflict("%s is still referenced by %s", resourceType, constraintErr.Table)
+		}
+		return errors.Validation("%s references a record that does not exist: %s", resourceType, fields)
+	case db.NotNullConstraint:
+		return errors.Validation("%s is missing required field %s", resourceType, fields)
+	case db.CheckConstraint:
+		return errors.Validation("%s fails check %s", resourceType, fields)
+	}
+	return nil
+}
+
+func handleAdvisoryLockError(err error) *errors.ServiceError {
+	if e.Is(err, db.ErrAdvisoryLockTimeout) {
+		return errors.DatabaseAdvisoryLockTimeout("%s", err.Error())
+	}
+	return errors.DatabaseAdvisoryLock(err)
+}
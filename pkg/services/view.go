@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	e "errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/auth"
+	"github.com/openshift-online/rh-trex/pkg/dao"
+	"github.com/openshift-online/rh-trex/pkg/errors"
+)
+
+// ExpandView expands the saved search of the caller the view of the list names into the arguments of the list,
+// before it runs. resourceList must be a pointer to a slice of database resource objects, the kind of the saved search.
+func (s *sqlGenericService) ExpandView(ctx context.Context, args *ListArguments, resourceList interface{}) *errors.ServiceError {
+	if args.View == "" {
+		return nil
+	}
+	resourceType := reflect.TypeOf(resourceList).Elem().Elem().Name()
+
+	var savedSearch api.SavedSearch
+	d := s.genericDao.GetInstanceDao(ctx, &savedSearch)
+	d.Where(dao.NewWhere("owner = ? AND resource_kind = ? AND name = ?", []any{auth.GetUsernameFromContext(ctx), resourceType, args.View}))
+	if err := d.Take(&savedSearch); err != nil {
+		if e.Is(err, gorm.ErrRecordNotFound) {
+			return errors.BadRequest("%s is not a saved search of %s", args.View, resourceType)
+		}
+		return handleGetError("SavedSearch", "name", args.View, err)
+	}
+
+	// the kind may have changed since the search was saved, its query must still apply to it
+	view := viewArguments(&savedSearch)
+	if err := s.checkView(ctx, view, resourceList); err != nil {
+		return errors.BadRequest("The saved search %s no longer applies to %s: %s", savedSearch.Name, resourceType, err.Reason)
+	}
+	applyView(args, view)
+	return nil
+}
+
+// checkView builds the search and the ordering of the view as a list would, without running it. The query limits
+// aren't checked, the list using the view rejects its query when it exceeds them.
+func (s *sqlGenericService) checkView(ctx context.Context, view *ListArguments, resourceList interface{}) *errors.ServiceError {
+	unguarded := &sqlGenericService{genericDao: s.genericDao}
+	listCtx, model, err := unguarded.newListContext(ctx, "", view, resourceList)
+	if err != nil {
+		return err
+	}
+	listCtx.set = map[string]bool{}
+
+	d := s.genericDao.GetInstanceDao(ctx, model)
+	for _, builderFn := range []listBuilder{unguarded.buildOrderBy, unguarded.buildSearch} {
+		if _, err := builderFn(listCtx, &d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// viewArguments returns the list arguments of the saved search
+func viewArguments(savedSearch *api.SavedSearch) *ListArguments {
+	return &ListArguments{
+		Search:  savedSearch.Search,
+		OrderBy: splitList(savedSearch.OrderBy),
+		Fields:  fieldsList(savedSearch.Fields),
+	}
+}
+
+// applyView narrows the search of the list with the one of the view, whose ordering and fields the list uses
+// unless it gives its own
+func applyView(args *ListArguments, view *ListArguments) {
+	switch {
+	case args.Search == "":
+		args.Search = view.Search
+	case view.Search != "":
+		args.Search = fmt.Sprintf("(%s) and (%s)", view.Search, args.Search)
+	}
+	if len(args.OrderBy) == 0 {
+		args.OrderBy = view.OrderBy
+	}
+	if len(args.Fields) == 0 {
+		args.Fields = view.Fields
+	}
+}
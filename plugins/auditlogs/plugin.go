@@ -0,0 +1,108 @@
+package auditlogs
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openshift-online/rh-trex/cmd/trex/environments"
+	"github.com/openshift-online/rh-trex/cmd/trex/environments/registry"
+	"github.com/openshift-online/rh-trex/cmd/trex/server"
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/api/presenters"
+	"github.com/openshift-online/rh-trex/pkg/auth"
+	"github.com/openshift-online/rh-trex/pkg/config"
+	"github.com/openshift-online/rh-trex/pkg/dao"
+	"github.com/openshift-online/rh-trex/pkg/db"
+	"github.com/openshift-online/rh-trex/pkg/handlers"
+	"github.com/openshift-online/rh-trex/pkg/services"
+	"github.com/openshift-online/rh-trex/plugins/generic"
+)
+
+// ServiceLocator Service Locator
+type ServiceLocator func() services.AuditLogService
+
+func NewServiceLocator(env *environments.Env) ServiceLocator {
+	return func() services.AuditLogService {
+		return services.NewAuditLogService(dao.NewAuditLogDao(&env.Database.SessionFactory))
+	}
+}
+
+// Service helper function to get the audit log service from the registry
+func Service(s *environments.Services) services.AuditLogService {
+	if s == nil {
+		return nil
+	}
+	if obj := s.GetService("AuditLogs"); obj != nil {
+		locator := obj.(ServiceLocator)
+		return locator()
+	}
+	return nil
+}
+
+func init() {
+	// Service registration
+	registry.RegisterService("AuditLogs", func(env interface{}) interface{} {
+		return NewServiceLocator(env.(*environments.Env))
+	})
+
+	// Middleware registration, every mutating call is recorded
+	server.RegisterMiddleware("auditlogs", func(services server.ServicesInterface) mux.MiddlewareFunc {
+		return handlers.NewAuditMiddleware(Service(services.(*environments.Services))).Audit
+	})
+
+	// Routes registration
+	server.RegisterRoutes("auditlogs", func(apiV1Router *mux.Router, services server.ServicesInterface, authMiddleware auth.JWTMiddleware, authzMiddleware auth.AuthorizationMiddleware) {
+		envServices := services.(*environments.Services)
+		auditLogHandler := handlers.NewAuditLogHandler(generic.Service(envServices))
+		adminMiddleware := auth.NewAdminMiddleware(environments.Environment().Config.Server.AdminUsers)
+
+		// audit logs are reserved to administrators
+		auditLogsRouter := apiV1Router.PathPrefix("/audit_logs").Subrouter()
+		auditLogsRouter.HandleFunc("", auditLogHandler.List).Methods(http.MethodGet)
+		auditLogsRouter.Use(authMiddleware.AuthenticateAccountJWT)
+		auditLogsRouter.Use(authzMiddleware.AuthorizeApi)
+		auditLogsRouter.Use(adminMiddleware.RequireAdmin)
+	})
+
+	// Purge registration
+	server.RegisterPurge("AuditLogs", func(services *environments.Services) server.PurgeFunc {
+		auditLogServices := Service(services)
+
+		return func(ctx context.Context, retention *config.RetentionConfig) (int64, error) {
+			if retention.AuditLogs <= 0 {
+				return 0, nil
+			}
+			purged, err := auditLogServices.PurgeBefore(ctx, time.Now().Add(-retention.AuditLogs))
+			if err != nil {
+				return 0, err
+			}
+			return purged, nil
+		}
+	})
+
+	// Search registration, the documents of the changes are left out
+	services.SearchSchemas["AuditLog"] = &db.SearchSchema{
+		Table: "audit_logs",
+		Fields: map[string]db.SearchField{
+			"id":            {Type: db.SearchString},
+			"created_at":    {Type: db.SearchTime},
+			"actor":         {Type: db.SearchString},
+			"operation_id":  {Type: db.SearchString},
+			"method":        {Type: db.SearchString},
+			"path":          {Type: db.SearchString},
+			"resource_kind": {Type: db.SearchString},
+			"resource_id":   {Type: db.SearchString},
+			"status_code":   {Type: db.SearchNumber},
+			"outcome":       {Type: db.SearchString},
+			"reason":        {Type: db.SearchString},
+		},
+	}
+
+	// Presenter registration
+	presenters.RegisterPath(api.AuditLog{}, "audit_logs")
+	presenters.RegisterPath(&api.AuditLog{}, "audit_logs")
+	presenters.RegisterKind(api.AuditLog{}, "AuditLog")
+	presenters.RegisterKind(&api.AuditLog{}, "AuditLog")
+}
@@ -1,7 +1,9 @@
 package dinosaurs
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/openshift-online/rh-trex/cmd/trex/environments"
@@ -10,6 +12,7 @@ import (
 	"github.com/openshift-online/rh-trex/pkg/api"
 	"github.com/openshift-online/rh-trex/pkg/api/presenters"
 	"github.com/openshift-online/rh-trex/pkg/auth"
+	"github.com/openshift-online/rh-trex/pkg/config"
 	"github.com/openshift-online/rh-trex/pkg/controllers"
 	"github.com/openshift-online/rh-trex/pkg/dao"
 	"github.com/openshift-online/rh-trex/pkg/db"
@@ -25,7 +28,7 @@ type ServiceLocator func() services.DinosaurService
 func NewServiceLocator(env *environments.Env) ServiceLocator {
 	return func() services.DinosaurService {
 		return services.NewDinosaurService(
-			db.NewAdvisoryLockFactory(env.Database.SessionFactory),
+			db.NewAdvisoryLockFactory(env.Database.SessionFactory, env.Config.Database),
 			dao.NewDinosaurDao(&env.Database.SessionFactory),
 			events.Service(&env.Services),
 		)
@@ -54,15 +57,27 @@ func init() {
 	server.RegisterRoutes("dinosaurs", func(apiV1Router *mux.Router, services server.ServicesInterface, authMiddleware auth.JWTMiddleware, authzMiddleware auth.AuthorizationMiddleware) {
 		envServices := services.(*environments.Services)
 		dinosaurHandler := handlers.NewDinosaurHandler(Service(envServices), generic.Service(envServices))
+		adminMiddleware := auth.NewAdminMiddleware(environments.Environment().Config.Server.AdminUsers)
+		// the writes are retried on serialization failures and deadlocks
+		retry := func(handler http.HandlerFunc) http.Handler {
+			return db.RetryTransaction(handler, environments.Environment().Database.SessionFactory, environments.Environment().Config.Database.TransactionRetries)
+		}
 
 		dinosaursRouter := apiV1Router.PathPrefix("/dinosaurs").Subrouter()
 		dinosaursRouter.HandleFunc("", dinosaurHandler.List).Methods(http.MethodGet)
+		// registered before "/{id}", which would take "aggregate" for an id
+		dinosaursRouter.HandleFunc("/aggregate", dinosaurHandler.Aggregate).Methods(http.MethodGet)
 		dinosaursRouter.HandleFunc("/{id}", dinosaurHandler.Get).Methods(http.MethodGet)
-		dinosaursRouter.HandleFunc("", dinosaurHandler.Create).Methods(http.MethodPost)
-		dinosaursRouter.HandleFunc("/{id}", dinosaurHandler.Patch).Methods(http.MethodPatch)
-		dinosaursRouter.HandleFunc("/{id}", dinosaurHandler.Delete).Methods(http.MethodDelete)
+		dinosaursRouter.HandleFunc("/{id}/history", dinosaurHandler.History).Methods(http.MethodGet)
+		dinosaursRouter.Handle("", retry(dinosaurHandler.Create)).Methods(http.MethodPost)
+		dinosaursRouter.Handle("/{id}", retry(dinosaurHandler.Patch)).Methods(http.MethodPatch)
+		dinosaursRouter.Handle("/{id}", retry(dinosaurHandler.Delete)).Methods(http.MethodDelete)
+		// restoring and purging soft-deleted dinosaurs is reserved to administrators
+		dinosaursRouter.Handle("/{id}/restore", adminMiddleware.RequireAdmin(retry(dinosaurHandler.Restore))).Methods(http.MethodPost)
+		dinosaursRouter.Handle("/{id}/purge", adminMiddleware.RequireAdmin(retry(dinosaurHandler.Purge))).Methods(http.MethodDelete)
 		dinosaursRouter.Use(authMiddleware.AuthenticateAccountJWT)
 		dinosaursRouter.Use(authzMiddleware.AuthorizeApi)
+		dinosaursRouter.Use(adminMiddleware.IdentifyAdmin)
 	})
 
 	// Controller registration
@@ -79,6 +94,34 @@ func init() {
 		})
 	})
 
+	// Purge registration
+	server.RegisterPurge("Dinosaurs", func(services *environments.Services) server.PurgeFunc {
+		dinoServices := Service(services)
+
+		return func(ctx context.Context, retention *config.RetentionConfig) (int64, error) {
+			if retention.SoftDeleted <= 0 {
+				return 0, nil
+			}
+			purged, err := dinoServices.PurgeDeleted(ctx, time.Now().Add(-retention.SoftDeleted))
+			if err != nil {
+				return 0, err
+			}
+			return purged, nil
+		}
+	})
+
+	// Search registration, the species are full-text searched in the column of addDinosaurTextSearch
+	services.FullTextSearchColumns["Dinosaur"] = "search_vector"
+	services.SearchSchemas["Dinosaur"] = &db.SearchSchema{
+		Table: "dinosaurs",
+		Fields: map[string]db.SearchField{
+			"id":         {Type: db.SearchString},
+			"species":    {Type: db.SearchString},
+			"created_at": {Type: db.SearchTime},
+			"updated_at": {Type: db.SearchTime},
+		},
+	}
+
 	// Presenter registration
 	presenters.RegisterPath(api.Dinosaur{}, "dinosaurs")
 	presenters.RegisterPath(&api.Dinosaur{}, "dinosaurs")
@@ -0,0 +1,65 @@
+package savedsearches
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/openshift-online/rh-trex/cmd/trex/environments"
+	"github.com/openshift-online/rh-trex/cmd/trex/environments/registry"
+	"github.com/openshift-online/rh-trex/cmd/trex/server"
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/api/presenters"
+	"github.com/openshift-online/rh-trex/pkg/auth"
+	"github.com/openshift-online/rh-trex/pkg/dao"
+	"github.com/openshift-online/rh-trex/pkg/handlers"
+	"github.com/openshift-online/rh-trex/pkg/services"
+)
+
+// ServiceLocator Service Locator
+type ServiceLocator func() services.SavedSearchService
+
+func NewServiceLocator(env *environments.Env) ServiceLocator {
+	return func() services.SavedSearchService {
+		return services.NewSavedSearchService(dao.NewSavedSearchDao(&env.Database.SessionFactory))
+	}
+}
+
+// Service helper function to get the saved search service from the registry
+func Service(s *environments.Services) services.SavedSearchService {
+	if s == nil {
+		return nil
+	}
+	if obj := s.GetService("SavedSearches"); obj != nil {
+		locator := obj.(ServiceLocator)
+		return locator()
+	}
+	return nil
+}
+
+func init() {
+	// Service registration
+	registry.RegisterService("SavedSearches", func(env interface{}) interface{} {
+		return NewServiceLocator(env.(*environments.Env))
+	})
+
+	// Routes registration, the saved searches are the caller's own
+	server.RegisterRoutes("saved_searches", func(apiV1Router *mux.Router, services server.ServicesInterface, authMiddleware auth.JWTMiddleware, authzMiddleware auth.AuthorizationMiddleware) {
+		envServices := services.(*environments.Services)
+		savedSearchHandler := handlers.NewSavedSearchHandler(Service(envServices))
+
+		savedSearchesRouter := apiV1Router.PathPrefix("/saved_searches").Subrouter()
+		savedSearchesRouter.HandleFunc("", savedSearchHandler.List).Methods(http.MethodGet)
+		savedSearchesRouter.HandleFunc("/{id}", savedSearchHandler.Get).Methods(http.MethodGet)
+		savedSearchesRouter.HandleFunc("", savedSearchHandler.Create).Methods(http.MethodPost)
+		savedSearchesRouter.HandleFunc("/{id}", savedSearchHandler.Patch).Methods(http.MethodPatch)
+		savedSearchesRouter.HandleFunc("/{id}", savedSearchHandler.Delete).Methods(http.MethodDelete)
+		savedSearchesRouter.Use(authMiddleware.AuthenticateAccountJWT)
+		savedSearchesRouter.Use(authzMiddleware.AuthorizeApi)
+	})
+
+	// Presenter registration
+	presenters.RegisterPath(api.SavedSearch{}, "saved_searches")
+	presenters.RegisterPath(&api.SavedSearch{}, "saved_searches")
+	presenters.RegisterKind(api.SavedSearch{}, "SavedSearch")
+	presenters.RegisterKind(&api.SavedSearch{}, "SavedSearch")
+}
@@ -112,6 +112,7 @@ func main() {
 			KindPlural:          fmt.Sprintf("%ss", kind),
 			KindLowerPlural:     kindLowerCamel + "s",
 			KindLowerSingular:   kindLowerCamel,
+			KindSnakeCase:       kindSnakeCase,
 			KindSnakeCasePlural: kindSnakeCase + "s",
 			Fields:              parsedFields,
 		}
@@ -242,30 +243,47 @@ func parseFields(fieldsStr string) ([]Field, error) {
 	fieldPairs := strings.Split(fieldsStr, ",")
 	for _, pair := range fieldPairs {
 		parts := strings.Split(strings.TrimSpace(pair), ":")
-		if len(parts) < 2 || len(parts) > 3 {
-			return nil, fmt.Errorf("invalid field format: %s (expected name:type or name:type:required)", pair)
+		if len(parts) < 2 || len(parts) > 4 {
+			return nil, fmt.Errorf("invalid field format: %s (expected name:type, name:type:required or name:type:searchable)", pair)
 		}
 
 		name := strings.TrimSpace(parts[0])
 		fieldType := strings.TrimSpace(parts[1])
 		nullable := true // Default to nullable
+		searchable := false
 
-		// Check for :required or :optional suffix
-		if len(parts) == 3 {
-			modifier := strings.TrimSpace(parts[2])
-			if modifier == "required" {
+		// Check for :required, :optional or :searchable suffixes
+		for _, modifier := range parts[2:] {
+			switch strings.TrimSpace(modifier) {
+			case "required":
 				nullable = false
-			} else if modifier == "optional" {
+			case "optional":
 				nullable = true
-			} else {
-				return nil, fmt.Errorf("invalid field modifier: %s (expected 'required' or 'optional')", modifier)
+			case "searchable":
+				searchable = true
+			default:
+				return nil, fmt.Errorf("invalid field modifier: %s (expected 'required', 'optional' or 'searchable')", modifier)
 			}
 		}
+		if searchable && fieldType != "string" {
+			return nil, fmt.Errorf("invalid field modifier: %s (only string fields are searchable)", pair)
+		}
+
+		// a ref is a relation to the kind of its name, which the field <name>_id holds the id of
+		refKind := ""
+		if fieldType == "ref" {
+			refKind, name, fieldType = toPascalCase(name), name+"_id", "string"
+		}
 
 		field, err := mapFieldType(name, fieldType, nullable)
 		if err != nil {
 			return nil, err
 		}
+		field.Searchable = searchable
+		if refKind != "" {
+			field.RefKind = refKind
+			field.RefSnakeCase = toSnakeCase(refKind)
+		}
 
 		fields = append(fields, field)
 	}
@@ -297,37 +315,54 @@ func mapFieldType(name, fieldType string, nullable bool) (Field, error) {
 		pointerType = "*string"
 		field.DBType = "text"
 		field.OpenAPIType = "string"
+		field.SearchType = "db.SearchString"
 	case "int":
 		baseType = "int"
 		pointerType = "*int"
 		field.DBType = "integer"
 		field.OpenAPIType = "integer"
 		field.OpenAPIFormat = "int32"
+		field.SearchType = "db.SearchNumber"
 	case "int64":
 		baseType = "int64"
 		pointerType = "*int64"
 		field.DBType = "bigint"
 		field.OpenAPIType = "integer"
 		field.OpenAPIFormat = "int64"
+		field.SearchType = "db.SearchNumber"
 	case "bool":
 		baseType = "bool"
 		pointerType = "*bool"
 		field.DBType = "boolean"
 		field.OpenAPIType = "boolean"
+		field.SearchType = "db.SearchBoolean"
 	case "float":
 		baseType = "float64"
 		pointerType = "*float64"
 		field.DBType = "double precision"
 		field.OpenAPIType = "number"
 		field.OpenAPIFormat = "double"
+		field.SearchType = "db.SearchNumber"
 	case "time":
 		baseType = "time.Time"
 		pointerType = "*time.Time"
 		field.DBType = "timestamp"
 		field.OpenAPIType = "string"
 		field.OpenAPIFormat = "date-time"
+		field.SearchType = "db.SearchTime"
+	case "json":
+		// free form properties, searched as `properties.<key>`. The searches only know the column
+		// db.PropertiesColumn, a json field of another name could be stored but never searched.
+		if snakeName != "properties" {
+			return field, fmt.Errorf("invalid json field: %s (the json field of a kind must be named properties)", name)
+		}
+		baseType = "Properties"
+		pointerType = "Properties"
+		field.DBType = "jsonb"
+		field.OpenAPIType = "object"
+		field.SearchType = "db.SearchProperties"
 	default:
-		return field, fmt.Errorf("unsupported field type: %s (supported types: string, int, int64, bool, float, time)", fieldType)
+		return field, fmt.Errorf("unsupported field type: %s (supported types: string, int, int64, bool, float, time, json, ref)", fieldType)
 	}
 
 	// Set GoType based on nullability
@@ -355,6 +390,35 @@ type Field struct {
 	Required      bool
 	Nullable      bool
 	PointerType   string
+	// Searchable fields are full-text searched by the "q" parameter
+	Searchable bool
+	// SearchType is the db.SearchFieldType of the field in the search schema, empty when it can't be searched
+	SearchType string
+	// RefKind is the kind the ref fields relate to, which the lists and the gets include as RefSnakeCase
+	RefKind      string
+	RefSnakeCase string
+}
+
+// RefFields returns the fields relating the kind to other kinds
+func (k myWriter) RefFields() []Field {
+	var fields []Field
+	for _, field := range k.Fields {
+		if field.RefKind != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// SearchableColumns returns the columns of the searchable fields, the text of the full-text search column
+func (k myWriter) SearchableColumns() []string {
+	var columns []string
+	for _, field := range k.Fields {
+		if field.Searchable {
+			columns = append(columns, field.NameSnakeCase)
+		}
+	}
+	return columns
 }
 
 type myWriter struct {
@@ -365,6 +429,7 @@ type myWriter struct {
 	KindPlural          string
 	KindLowerPlural     string
 	KindLowerSingular   string
+	KindSnakeCase       string
 	KindSnakeCasePlural string
 	ID                  string
 	Fields              []Field
@@ -6,6 +6,7 @@ import (
 
 	"github.com/openshift-online/rh-trex/cmd/trex/environments"
 	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/auth"
 	"github.com/openshift-online/rh-trex/plugins/dinosaurs"
 )
 
@@ -16,7 +17,8 @@ func (f *Factories) NewDinosaur(species string) (*api.Dinosaur, error) {
 		Species: species,
 	}
 
-	dino, err := dinoService.Create(context.Background(), dinosaur)
+	ctx := auth.SetTenantContext(context.Background(), DefaultOrgID)
+	dino, err := dinoService.Create(ctx, dinosaur)
 	if err != nil {
 		return nil, err
 	}
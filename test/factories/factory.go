@@ -2,6 +2,9 @@ package factories
 
 import "github.com/segmentio/ksuid"
 
+// DefaultOrgID is the organization of the test accounts, and of the resources created by the factories
+const DefaultOrgID = "trex-test-org"
+
 type Factories struct {
 }
 
@@ -247,7 +247,16 @@ func (helper *Helper) NewRandAccount() *amv1.Account {
 	return helper.NewAccount(helper.NewID(), faker.Name(), faker.Email())
 }
 
+// NewRandAccountInOrganization returns an account of the given organization instead of factories.DefaultOrgID
+func (helper *Helper) NewRandAccountInOrganization(orgID string) *amv1.Account {
+	return helper.newAccount(helper.NewID(), faker.Name(), faker.Email(), orgID)
+}
+
 func (helper *Helper) NewAccount(username, name, email string) *amv1.Account {
+	return helper.newAccount(username, name, email, factories.DefaultOrgID)
+}
+
+func (helper *Helper) newAccount(username, name, email, orgID string) *amv1.Account {
 	var firstName string
 	var lastName string
 	names := strings.SplitN(name, " ", 2)
@@ -263,7 +272,8 @@ func (helper *Helper) NewAccount(username, name, email string) *amv1.Account {
 		Username(username).
 		FirstName(firstName).
 		LastName(lastName).
-		Email(email)
+		Email(email).
+		Organization(amv1.NewOrganization().ID(orgID))
 
 	acct, err := builder.Build()
 	if err != nil {
@@ -374,6 +384,9 @@ func (helper *Helper) CreateJWTString(account *amv1.Account) string {
 	if account.Email() != "" {
 		claims["email"] = account.Email()
 	}
+	if orgID := account.Organization().ID(); orgID != "" {
+		claims["org_id"] = orgID
+	}
 	/* TODO the ocm api model needs to be updated to expose this
 	if account.ServiceAccount {
 		claims["clientId"] = account.Username()
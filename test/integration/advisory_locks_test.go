@@ -0,0 +1,126 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/config"
+	"github.com/openshift-online/rh-trex/pkg/dao"
+	"github.com/openshift-online/rh-trex/pkg/db"
+	"github.com/openshift-online/rh-trex/pkg/services"
+	"github.com/openshift-online/rh-trex/plugins/events"
+	"github.com/openshift-online/rh-trex/test"
+)
+
+func TestAdvisoryLockTimeout(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	dbConfig := *h.Env().Config.Database
+	dbConfig.AdvisoryLockTimeout = 200 * time.Millisecond
+	lockFactory := db.NewAdvisoryLockFactory(h.Env().Database.SessionFactory, &dbConfig)
+
+	ctx := context.Background()
+	id := h.NewID()
+
+	holder, err := lockFactory.NewAdvisoryLock(ctx, id, db.Dinosaurs)
+	Expect(err).NotTo(HaveOccurred())
+
+	// a second caller gives up after the lock timeout
+	start := time.Now()
+	_, err = lockFactory.NewAdvisoryLock(ctx, id, db.Dinosaurs)
+	Expect(err).To(HaveOccurred())
+	Expect(errors.Is(err, db.ErrAdvisoryLockTimeout)).To(BeTrue(), "unexpected error: %v", err)
+	Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+
+	// a cancelled context stops waiting as well
+	unbounded := db.NewAdvisoryLockFactory(h.Env().Database.SessionFactory, &config.DatabaseConfig{})
+	cancelCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	_, err = unbounded.NewAdvisoryLock(cancelCtx, id, db.Dinosaurs)
+	Expect(err).To(HaveOccurred())
+	Expect(errors.Is(err, db.ErrAdvisoryLockTimeout)).To(BeTrue(), "unexpected error: %v", err)
+
+	// once released, the lock can be obtained again
+	lockFactory.Unlock(ctx, holder)
+	owner, err := lockFactory.NewAdvisoryLock(ctx, id, db.Dinosaurs)
+	Expect(err).NotTo(HaveOccurred())
+	lockFactory.Unlock(ctx, owner)
+}
+
+func TestSessionAdvisoryLock(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	dbConfig := *h.Env().Config.Database
+	dbConfig.AdvisoryLockTimeout = 200 * time.Millisecond
+	lockFactory := db.NewSessionAdvisoryLockFactory(h.Env().Database.SessionFactory, &dbConfig)
+
+	ctx := db.WithAdvisoryLockScope(context.Background())
+	id := h.NewID()
+
+	owner, err := lockFactory.NewAdvisoryLock(ctx, id, db.Dinosaurs)
+	Expect(err).NotTo(HaveOccurred())
+
+	// nested calls sharing the context re-enter the lock
+	nested, err := lockFactory.NewAdvisoryLock(ctx, id, db.Dinosaurs)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(nested).To(Equal(owner))
+
+	// other contexts wait for it
+	_, acquired, err := lockFactory.NewNonBlockingLock(context.Background(), id, db.Dinosaurs)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(acquired).To(BeFalse())
+	_, err = lockFactory.NewAdvisoryLock(context.Background(), id, db.Dinosaurs)
+	Expect(errors.Is(err, db.ErrAdvisoryLockTimeout)).To(BeTrue(), "unexpected error: %v", err)
+
+	// the lock is held until the outermost caller unlocks it
+	lockFactory.Unlock(ctx, nested)
+	_, acquired, err = lockFactory.NewNonBlockingLock(context.Background(), id, db.Dinosaurs)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(acquired).To(BeFalse())
+
+	lockFactory.Unlock(ctx, owner)
+	other, acquired, err := lockFactory.NewNonBlockingLock(context.Background(), id, db.Dinosaurs)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(acquired).To(BeTrue())
+	lockFactory.Unlock(ctx, other)
+}
+
+func TestSessionAdvisoryLockNestedServiceCall(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	dino, err := h.Factories.NewDinosaur(h.NewID())
+	Expect(err).NotTo(HaveOccurred())
+
+	dbConfig := *h.Env().Config.Database
+	dbConfig.AdvisoryLockTimeout = 200 * time.Millisecond
+	lockFactory := db.NewSessionAdvisoryLockFactory(h.Env().Database.SessionFactory, &dbConfig)
+	ctx := db.WithAdvisoryLockScope(context.Background())
+
+	owner, err := lockFactory.NewAdvisoryLock(ctx, dino.ID, db.Dinosaurs)
+	Expect(err).NotTo(HaveOccurred())
+
+	// a service using session locks locks the dinosaur through a factory of its own, re-entering the lock of the context
+	dinoService := services.NewDinosaurService(
+		db.NewSessionAdvisoryLockFactory(h.Env().Database.SessionFactory, &dbConfig),
+		dao.NewDinosaurDao(&h.Env().Database.SessionFactory),
+		events.Service(&h.Env().Services),
+	)
+	replaced, svcErr := dinoService.Replace(ctx, &api.Dinosaur{Meta: api.Meta{ID: dino.ID}, Species: "nested"})
+	Expect(svcErr).To(BeNil())
+	Expect(replaced.Species).To(Equal("nested"))
+	_, acquired, err := lockFactory.NewNonBlockingLock(context.Background(), dino.ID, db.Dinosaurs)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(acquired).To(BeFalse())
+
+	// the lock is released once the outermost caller unlocks it
+	lockFactory.Unlock(ctx, owner)
+	other, acquired, err := lockFactory.NewNonBlockingLock(context.Background(), dino.ID, db.Dinosaurs)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(acquired).To(BeTrue())
+	lockFactory.Unlock(context.Background(), other)
+}
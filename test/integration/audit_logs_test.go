@@ -0,0 +1,103 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/api/openapi"
+	"github.com/openshift-online/rh-trex/pkg/services"
+	"github.com/openshift-online/rh-trex/plugins/generic"
+	"github.com/openshift-online/rh-trex/test"
+	"github.com/openshift-online/rh-trex/test/factories"
+
+	_ "github.com/openshift-online/rh-trex/plugins/auditlogs"
+)
+
+func TestAuditLogsRecordMutatingCalls(t *testing.T) {
+	h, client := test.RegisterIntegration(t)
+
+	account := h.NewRandAccount()
+	ctx := h.NewAuthenticatedContext(account)
+
+	dino, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursPost(ctx).Dinosaur(openapi.Dinosaur{Species: "Stegosaurus"}).Execute()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+	species := "Triceratops"
+	_, _, err = client.DefaultAPI.ApiRhTrexV1DinosaursIdPatch(ctx, *dino.Id).DinosaurPatchRequest(openapi.DinosaurPatchRequest{Species: &species}).Execute()
+	Expect(err).NotTo(HaveOccurred())
+
+	_, resp, err = client.DefaultAPI.ApiRhTrexV1DinosaursIdPatch(ctx, "missing").DinosaurPatchRequest(openapi.DinosaurPatchRequest{Species: &species}).Execute()
+	Expect(err).To(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	// reads are not audited
+	_, _, err = client.DefaultAPI.ApiRhTrexV1DinosaursIdGet(ctx, *dino.Id).Execute()
+	Expect(err).NotTo(HaveOccurred())
+
+	var auditLogs []api.AuditLog
+	listArgs := &services.ListArguments{Page: 1, Size: 10, OrderBy: []string{"created_at asc"}}
+	_, svcErr := generic.Service(&h.Env().Services).List(context.Background(), "username", listArgs, &auditLogs)
+	Expect(svcErr).To(BeNil())
+	Expect(auditLogs).To(HaveLen(3))
+
+	created, patched, failed := auditLogs[0], auditLogs[1], auditLogs[2]
+	Expect(created.Actor).To(Equal(account.Username()))
+	Expect(created.Method).To(Equal(http.MethodPost))
+	Expect(created.ResourceKind).To(Equal("Dinosaur"))
+	Expect(created.ResourceID).To(Equal(*dino.Id))
+	Expect(created.Before).To(BeNil())
+	Expect(created.Outcome).To(Equal(api.AuditSuccess))
+	Expect(created.StatusCode).To(Equal(http.StatusCreated))
+
+	Expect(patched.Method).To(Equal(http.MethodPatch))
+	Expect(patched.OperationID).NotTo(BeEmpty())
+	Expect(*patched.Changes).To(ContainSubstring(`"Species":{"before":"Stegosaurus","after":"Triceratops"}`))
+
+	Expect(failed.ResourceID).To(Equal("missing"))
+	Expect(failed.Outcome).To(Equal(api.AuditFailure))
+	Expect(failed.StatusCode).To(Equal(http.StatusNotFound))
+	Expect(failed.Reason).To(ContainSubstring("not found"))
+}
+
+func TestAuditLogsRecordCommitOutcome(t *testing.T) {
+	h, client := test.RegisterIntegration(t)
+
+	account := h.NewRandAccount()
+	ctx := h.NewAuthenticatedContext(account)
+
+	dino, err := h.Factories.NewDinosaur("Stegosaurus")
+	Expect(err).NotTo(HaveOccurred())
+
+	// the updates to the species of this test fail once the transaction commits, after the response is written
+	g2 := h.Env().Database.SessionFactory.New(context.Background())
+	Expect(g2.Exec(`create function test_fail_commit() returns trigger as $$
+		begin
+			if new.species = 'Uncommitted' then
+				raise exception 'forced commit failure' using errcode = 'check_violation';
+			end if;
+			return new;
+		end $$ language plpgsql`).Error).NotTo(HaveOccurred())
+	defer g2.Exec("drop function test_fail_commit")
+	Expect(g2.Exec("create constraint trigger test_fail_commit after update on dinosaurs deferrable initially deferred for each row execute function test_fail_commit()").Error).NotTo(HaveOccurred())
+	defer g2.Exec("drop trigger test_fail_commit on dinosaurs")
+
+	species := "Uncommitted"
+	_, _, _ = client.DefaultAPI.ApiRhTrexV1DinosaursIdPatch(ctx, dino.ID).DinosaurPatchRequest(openapi.DinosaurPatchRequest{Species: &species}).Execute()
+
+	// the audit log is recorded once the transaction is resolved, with the outcome of the commit
+	var auditLogs []api.AuditLog
+	listArgs := &services.ListArguments{Page: 1, Size: 10, Search: fmt.Sprintf("resource_id = '%s'", dino.ID)}
+	_, svcErr := generic.Service(&h.Env().Services).List(context.Background(), "username", listArgs, &auditLogs)
+	Expect(svcErr).To(BeNil())
+	Expect(auditLogs).To(HaveLen(1))
+	Expect(auditLogs[0].Actor).To(Equal(account.Username()))
+	Expect(auditLogs[0].OrgID).To(Equal(factories.DefaultOrgID))
+	Expect(auditLogs[0].Outcome).To(Equal(api.AuditFailure))
+	Expect(auditLogs[0].Reason).To(Equal("The changes of the call were not committed"))
+}
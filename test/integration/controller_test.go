@@ -56,8 +56,9 @@ func TestControllerRacing(t *testing.T) {
 		go func() {
 			s := &server.ControllersServer{
 				KindControllerManager: controllers.NewKindControllerManager(
-					db.NewAdvisoryLockFactory(h.Env().Database.SessionFactory),
+					db.NewAdvisoryLockFactory(h.Env().Database.SessionFactory, h.Env().Config.Database),
 					events.Service(&h.Env().Services),
+					h.Env().Database.SessionFactory,
 				),
 			}
 
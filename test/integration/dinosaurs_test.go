@@ -2,21 +2,29 @@ package integration
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/api/presenters"
+	"github.com/openshift-online/rh-trex/pkg/auth"
 	"github.com/openshift-online/rh-trex/pkg/dao"
 	"github.com/openshift-online/rh-trex/pkg/services"
+	"github.com/openshift-online/rh-trex/plugins/dinosaurs"
+	"github.com/openshift-online/rh-trex/plugins/generic"
 
 	. "github.com/onsi/gomega"
 	"gopkg.in/resty.v1"
 
 	"github.com/openshift-online/rh-trex/pkg/api/openapi"
 	"github.com/openshift-online/rh-trex/test"
+	"github.com/openshift-online/rh-trex/test/factories"
 )
 
 func TestDinosaurGet(t *testing.T) {
@@ -144,6 +152,60 @@ func contains(et api.EventType, events api.EventList) bool {
 	return false
 }
 
+func TestDinosaurWriteRetry(t *testing.T) {
+	h, client := test.RegisterIntegration(t)
+
+	account := h.NewRandAccount()
+	ctx := h.NewAuthenticatedContext(account)
+
+	dino, err := h.Factories.NewDinosaur("Brontosaurus")
+	Expect(err).NotTo(HaveOccurred())
+
+	// the first attempt of every write of the species of this test fails with a serialization failure,
+	// the sequence counting the attempts isn't rolled back with the failed transactions
+	g2 := h.Env().Database.SessionFactory.New(context.Background())
+	Expect(g2.Exec("create sequence test_retried_updates").Error).NotTo(HaveOccurred())
+	defer g2.Exec("drop sequence test_retried_updates")
+	Expect(g2.Exec(`create function test_fail_first_update() returns trigger as $$
+		begin
+			if new.species like 'Retried%' and nextval('test_retried_updates') % 2 = 1 then
+				raise exception 'forced serialization failure' using errcode = 'serialization_failure';
+			end if;
+			return new;
+		end $$ language plpgsql`).Error).NotTo(HaveOccurred())
+	defer g2.Exec("drop function test_fail_first_update")
+	Expect(g2.Exec("create trigger test_fail_first_update before insert or update on dinosaurs for each row execute function test_fail_first_update()").Error).NotTo(HaveOccurred())
+	defer g2.Exec("drop trigger test_fail_first_update on dinosaurs")
+
+	// the request transaction is rolled back and the patch succeeds in a new one
+	species := "Retried"
+	dinosaur, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursIdPatch(ctx, dino.ID).DinosaurPatchRequest(openapi.DinosaurPatchRequest{Species: &species}).Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error patching object:  %v", err)
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	Expect(dinosaur.Species).To(Equal(species))
+
+	var updates int64
+	Expect(g2.Raw("select last_value from test_retried_updates").Scan(&updates).Error).NotTo(HaveOccurred())
+	Expect(updates).To(Equal(int64(2)))
+	found, svcErr := dinosaurs.Service(&h.Env().Services).Get(context.Background(), dino.ID)
+	Expect(svcErr).To(BeNil())
+	Expect(found.Species).To(Equal(species))
+
+	// the advisory lock of the failed attempt was released, the dinosaur can be patched again right away
+	species = "Patched"
+	_, resp, err = client.DefaultAPI.ApiRhTrexV1DinosaursIdPatch(ctx, dino.ID).DinosaurPatchRequest(openapi.DinosaurPatchRequest{Species: &species}).Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error patching object:  %v", err)
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	// the creates are retried too
+	created, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursPost(ctx).Dinosaur(openapi.Dinosaur{Species: "Retried create"}).Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error posting object:  %v", err)
+	Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+	Expect(created.Species).To(Equal("Retried create"))
+	Expect(g2.Raw("select last_value from test_retried_updates").Scan(&updates).Error).NotTo(HaveOccurred())
+	Expect(updates).To(Equal(int64(4)))
+}
+
 func TestDinosaurPaging(t *testing.T) {
 	h, client := test.RegisterIntegration(t)
 
@@ -280,3 +342,505 @@ func testUpdateDinosaurWithRacingRequests(t *testing.T, useAdvisoryLock, useBloc
 		return nil
 	}, 5*time.Second, 1*time.Second).Should(Succeed())
 }
+
+func TestDinosaurRestoreAndPurge(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	dinoService := dinosaurs.Service(&h.Env().Services)
+	genericService := generic.Service(&h.Env().Services)
+	ctx := context.Background()
+
+	dino, err := h.Factories.NewDinosaur("Triceratops")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(dinoService.Delete(ctx, dino.ID)).To(BeNil())
+
+	// deleted dinosaurs are only listed when asked for, by administrators
+	var deleted []api.Dinosaur
+	_, svcErr := genericService.List(ctx, "username", &services.ListArguments{Page: 1, Size: 10, Deleted: true}, &deleted)
+	Expect(svcErr).NotTo(BeNil())
+	Expect(svcErr.HttpCode).To(Equal(http.StatusForbidden))
+	_, svcErr = genericService.Aggregate(ctx, "username", &services.AggregateArguments{Deleted: true, Aggregates: []string{services.AggregateCount}}, &deleted)
+	Expect(svcErr).NotTo(BeNil())
+	Expect(svcErr.HttpCode).To(Equal(http.StatusForbidden))
+	_, svcErr = genericService.List(auth.SetAdminContext(ctx), "username", &services.ListArguments{Page: 1, Size: 10, Deleted: true}, &deleted)
+	Expect(svcErr).To(BeNil())
+	Expect(deleted).To(HaveLen(1))
+	Expect(deleted[0].ID).To(Equal(dino.ID))
+
+	restored, svcErr := dinoService.Restore(ctx, dino.ID)
+	Expect(svcErr).To(BeNil())
+	Expect(restored.ID).To(Equal(dino.ID))
+	Expect(restored.DeletedAt.Valid).To(BeFalse())
+
+	// only deleted dinosaurs can be restored
+	_, svcErr = dinoService.Restore(ctx, dino.ID)
+	Expect(svcErr).NotTo(BeNil())
+	Expect(svcErr.HttpCode).To(Equal(http.StatusNotFound))
+
+	Expect(dinoService.Delete(ctx, dino.ID)).To(BeNil())
+	purged, svcErr := dinoService.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+	Expect(svcErr).To(BeNil())
+	Expect(purged).To(BeZero(), "dinosaurs deleted within the retention must be kept")
+
+	purged, svcErr = dinoService.PurgeDeleted(ctx, time.Now())
+	Expect(svcErr).To(BeNil())
+	Expect(purged).To(Equal(int64(1)))
+
+	_, svcErr = dinoService.Restore(ctx, dino.ID)
+	Expect(svcErr.HttpCode).To(Equal(http.StatusNotFound))
+
+	// purging a live dinosaur deletes it permanently too
+	dino, err = h.Factories.NewDinosaur("Stegosaurus")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(dinoService.Purge(ctx, dino.ID)).To(BeNil())
+	svcErr = dinoService.Purge(ctx, dino.ID)
+	Expect(svcErr.HttpCode).To(Equal(http.StatusNotFound))
+}
+
+func TestDinosaurHistory(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	dinoService := dinosaurs.Service(&h.Env().Services)
+	ctx := context.Background()
+
+	dino, err := h.Factories.NewDinosaur("Brontosaurus")
+	Expect(err).NotTo(HaveOccurred())
+	created := time.Now()
+
+	dino.Species = "Apatosaurus"
+	_, svcErr := dinoService.Replace(ctx, dino)
+	Expect(svcErr).To(BeNil())
+	updated := time.Now()
+
+	Expect(dinoService.Delete(ctx, dino.ID)).To(BeNil())
+
+	versions, svcErr := dinoService.History(ctx, dino.ID)
+	Expect(svcErr).To(BeNil())
+	Expect(versions).To(HaveLen(3))
+	Expect(versions[0].Operation).To(Equal(api.CreateEventType))
+	Expect(versions[1].Operation).To(Equal(api.UpdateEventType))
+	Expect(versions[2].Operation).To(Equal(api.DeleteEventType))
+	Expect(versions[0].ValidTo).NotTo(BeNil())
+	Expect(versions[2].ValidTo).To(BeNil())
+
+	asOf, svcErr := dinoService.GetAsOf(ctx, dino.ID, created)
+	Expect(svcErr).To(BeNil())
+	Expect(asOf.Species).To(Equal("Brontosaurus"))
+
+	asOf, svcErr = dinoService.GetAsOf(ctx, dino.ID, updated)
+	Expect(svcErr).To(BeNil())
+	Expect(asOf.Species).To(Equal("Apatosaurus"))
+
+	// the dinosaur didn't exist before it was created nor after it was deleted
+	_, svcErr = dinoService.GetAsOf(ctx, dino.ID, created.Add(-time.Hour))
+	Expect(svcErr.HttpCode).To(Equal(http.StatusNotFound))
+	_, svcErr = dinoService.GetAsOf(ctx, dino.ID, time.Now())
+	Expect(svcErr.HttpCode).To(Equal(http.StatusNotFound))
+
+	_, svcErr = dinoService.History(ctx, "missing")
+	Expect(svcErr.HttpCode).To(Equal(http.StatusNotFound))
+}
+
+func TestDinosaurTenantIsolation(t *testing.T) {
+	h, client := test.RegisterIntegration(t)
+
+	ctx := h.NewAuthenticatedContext(h.NewRandAccount())
+	otherCtx := h.NewAuthenticatedContext(h.NewRandAccountInOrganization(h.NewID()))
+
+	dino, err := h.Factories.NewDinosaur("Velociraptor")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(dino.OrgID).To(Equal(factories.DefaultOrgID))
+
+	list, _, err := client.DefaultAPI.ApiRhTrexV1DinosaursGet(otherCtx).Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	Expect(list.Items).To(BeEmpty())
+	Expect(list.Total).To(Equal(int32(0)))
+
+	// the dinosaurs of other organizations don't exist for the caller
+	_, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursIdGet(otherCtx, dino.ID).Execute()
+	Expect(err).To(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	species := "Utahraptor"
+	_, resp, err = client.DefaultAPI.ApiRhTrexV1DinosaursIdPatch(otherCtx, dino.ID).DinosaurPatchRequest(openapi.DinosaurPatchRequest{Species: &species}).Execute()
+	Expect(err).To(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	created, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursPost(otherCtx).Dinosaur(openapi.Dinosaur{Species: species}).Execute()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+	list, _, err = client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	Expect(list.Items).To(HaveLen(1))
+	Expect(*list.Items[0].Id).To(Equal(dino.ID))
+
+	// the controllers and jobs are not confined to any organization
+	found, svcErr := dinosaurs.Service(&h.Env().Services).Get(context.Background(), *created.Id)
+	Expect(svcErr).To(BeNil())
+	Expect(found.OrgID).NotTo(Equal(factories.DefaultOrgID))
+
+	// the callers without organization only reach the records without one, unless --require-org-id rejects them
+	list, resp, err = client.DefaultAPI.ApiRhTrexV1DinosaursGet(h.NewAuthenticatedContext(h.NewRandAccountInOrganization(""))).Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	for _, listed := range list.Items {
+		Expect(*listed.Id).NotTo(BeElementOf(dino.ID, *created.Id))
+	}
+}
+
+func TestDinosaurLabels(t *testing.T) {
+	h, client := test.RegisterIntegration(t)
+
+	ctx := h.NewAuthenticatedContext(h.NewRandAccountInOrganization(h.NewID()))
+
+	create := func(species string, labels map[string]string) openapi.Dinosaur {
+		dino, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursPost(ctx).Dinosaur(openapi.Dinosaur{Species: species, Labels: &labels}).Execute()
+		Expect(err).NotTo(HaveOccurred(), "Error posting object:  %v", err)
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		return *dino
+	}
+	gold := create("Stegosaurus", map[string]string{"tier": "gold", "example.com/diet": "herbivore"})
+	silver := create("Allosaurus", map[string]string{"tier": "silver"})
+	bare := create("Triceratops", map[string]string{})
+	Expect(gold.GetLabels()).To(HaveKeyWithValue("example.com/diet", "herbivore"))
+	Expect(bare.GetLabels()).To(BeEmpty())
+
+	selected := func(selector string) []string {
+		list, _, err := client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).LabelSelector(selector).Execute()
+		Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+		var ids []string
+		for _, dino := range list.Items {
+			ids = append(ids, *dino.Id)
+		}
+		return ids
+	}
+	Expect(selected("tier=gold")).To(ConsistOf(*gold.Id))
+	Expect(selected("tier in (gold, silver)")).To(ConsistOf(*gold.Id, *silver.Id))
+	Expect(selected("tier notin (gold)")).To(ConsistOf(*silver.Id, *bare.Id))
+	Expect(selected("tier!=silver,example.com/diet")).To(ConsistOf(*gold.Id))
+	Expect(selected("!tier")).To(ConsistOf(*bare.Id))
+
+	// patching the labels replaces them
+	labels := map[string]string{"tier": "gold"}
+	patched, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursIdPatch(ctx, *silver.Id).DinosaurPatchRequest(openapi.DinosaurPatchRequest{Labels: &labels}).Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error patching object:  %v", err)
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	Expect(patched.GetLabels()).To(Equal(labels))
+	Expect(patched.Species).To(Equal("Allosaurus"))
+	Expect(selected("tier=gold")).To(ConsistOf(*gold.Id, *silver.Id))
+
+	invalid := map[string]string{"-tier": "gold"}
+	_, resp, err = client.DefaultAPI.ApiRhTrexV1DinosaursPost(ctx).Dinosaur(openapi.Dinosaur{Species: "Diplodocus", Labels: &invalid}).Execute()
+	Expect(err).To(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+	_, resp, err = client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).LabelSelector("tier in ()").Execute()
+	Expect(err).To(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+}
+
+func TestDinosaurContinue(t *testing.T) {
+	h, client := test.RegisterIntegration(t)
+
+	ctx := h.NewAuthenticatedContext(h.NewRandAccountInOrganization(h.NewID()))
+
+	create := func(species string) string {
+		dino, _, err := client.DefaultAPI.ApiRhTrexV1DinosaursPost(ctx).Dinosaur(openapi.Dinosaur{Species: species}).Execute()
+		Expect(err).NotTo(HaveOccurred(), "Error posting object:  %v", err)
+		return *dino.Id
+	}
+	for _, species := range []string{"Gallimimus", "Allosaurus", "Diplodocus", "Allosaurus", "Ceratosaurus", "Baryonyx", "Edmontosaurus"} {
+		create(species)
+	}
+
+	list, _, err := client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).OrderBy("species desc").Size(3).Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	Expect(list.Total).To(Equal(int32(7)))
+	Expect(list.HasContinue()).To(BeTrue())
+
+	// the dinosaurs added meanwhile before the token neither shift nor repeat the next pages
+	create("Herrerasaurus")
+
+	var species []string
+	seen := map[string]bool{}
+	for {
+		for _, dino := range list.Items {
+			Expect(seen).NotTo(HaveKey(*dino.Id))
+			seen[*dino.Id] = true
+			species = append(species, dino.Species)
+		}
+		if !list.HasContinue() {
+			break
+		}
+		list, _, err = client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).OrderBy("species desc").Size(3).Continue_(list.GetContinue()).Execute()
+		Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+		Expect(list.Total).To(Equal(int32(8)))
+	}
+	Expect(species).To(Equal([]string{"Gallimimus", "Edmontosaurus", "Diplodocus", "Ceratosaurus", "Baryonyx", "Allosaurus", "Allosaurus"}))
+
+	// the tokens are only valid for the query they were issued for
+	list, _, err = client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).OrderBy("species desc").Size(3).Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	_, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).OrderBy("species asc").Size(3).Continue_(list.GetContinue()).Execute()
+	Expect(err).To(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+	_, resp, err = client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).Continue_("garbage").Execute()
+	Expect(err).To(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+}
+
+func TestDinosaurListTotal(t *testing.T) {
+	h, client := test.RegisterIntegration(t)
+
+	account := h.NewRandAccount()
+	ctx := h.NewAuthenticatedContext(account)
+
+	_, err := h.Factories.NewDinosaurList("Bronto", 5)
+	Expect(err).NotTo(HaveOccurred())
+
+	list, _, err := client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).Total("exact").Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	Expect(list.Total).To(Equal(int32(5)))
+
+	list, _, err = client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).Total("none").Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	Expect(list.Total).To(Equal(int32(-1)))
+	Expect(list.Items).To(HaveLen(5))
+
+	// the estimates come from the planner statistics, which don't follow the inserts closely
+	list, _, err = client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).Search("species like 'Bronto_%'").Total("estimate").Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	Expect(list.Total).To(BeNumerically(">=", 0))
+	Expect(list.Items).To(HaveLen(5))
+
+	_, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).Total("approximately").Execute()
+	Expect(err).To(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+}
+
+func TestDinosaurListFields(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	ctx := h.NewAuthenticatedContext(h.NewRandAccount())
+	jwtToken := ctx.Value(openapi.ContextAccessToken)
+
+	_, err := h.Factories.NewDinosaurList("Bronto", 3)
+	Expect(err).NotTo(HaveOccurred())
+
+	var list presenters.ProjectionList
+	restyResp, err := resty.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+		SetQueryParams(map[string]string{"fields": "species,href", "orderBy": "species desc", "size": "2"}).
+		SetResult(&list).
+		Get(h.RestURL("/dinosaurs"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(restyResp.StatusCode()).To(Equal(http.StatusOK))
+
+	Expect(list.Kind).To(Equal("DinosaurList"))
+	Expect(list.Total).To(Equal(int32(3)))
+	Expect(list.Continue).NotTo(BeEmpty())
+	Expect(list.Items).To(HaveLen(2))
+	for i, species := range []string{"Bronto_3", "Bronto_2"} {
+		Expect(list.Items[i]).To(HaveKeyWithValue("species", species))
+		Expect(list.Items[i]).To(HaveKey("id"))
+		Expect(list.Items[i]).To(HaveKey("href"))
+		Expect(list.Items[i]).NotTo(HaveKey("created_at"))
+	}
+
+	restyResp, err = resty.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+		SetQueryParam("fields", "species,teeth").
+		Get(h.RestURL("/dinosaurs"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(restyResp.StatusCode()).To(Equal(http.StatusBadRequest))
+}
+
+func TestDinosaurAggregate(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	ctx := h.NewAuthenticatedContext(h.NewRandAccount())
+	jwtToken := ctx.Value(openapi.ContextAccessToken)
+
+	for _, species := range []string{"Stego", "Stego", "Bronto"} {
+		_, err := h.Factories.NewDinosaur(species)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	var aggregations presenters.AggregationList
+	restyResp, err := resty.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+		SetQueryParams(map[string]string{"groupBy": "species", "aggregates": "count,max(created_at)"}).
+		SetResult(&aggregations).
+		Get(h.RestURL("/dinosaurs/aggregate"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(restyResp.StatusCode()).To(Equal(http.StatusOK))
+
+	Expect(aggregations.Kind).To(Equal("DinosaurAggregationList"))
+	Expect(aggregations.Items).To(HaveLen(2))
+	for i, group := range []struct {
+		species string
+		count   int64
+	}{{"Bronto", 1}, {"Stego", 2}} {
+		Expect(aggregations.Items[i].Group).To(HaveKeyWithValue("species", group.species))
+		Expect(*aggregations.Items[i].Count).To(Equal(group.count))
+		Expect(aggregations.Items[i].Max).To(HaveKey("created_at"))
+		Expect(aggregations.Items[i].Min).To(BeNil())
+	}
+
+	// the search filters the dinosaurs before they are grouped
+	restyResp, err = resty.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+		SetQueryParams(map[string]string{"search": "species = 'Stego'", "aggregates": "count,min(species)"}).
+		SetResult(&aggregations).
+		Get(h.RestURL("/dinosaurs/aggregate"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(restyResp.StatusCode()).To(Equal(http.StatusOK))
+	Expect(aggregations.Items).To(HaveLen(1))
+	Expect(*aggregations.Items[0].Count).To(Equal(int64(2)))
+	Expect(aggregations.Items[0].Min).To(HaveKeyWithValue("species", "Stego"))
+
+	for _, params := range []map[string]string{
+		{"groupBy": "labels"},
+		{"groupBy": "teeth"},
+		{"aggregates": "sum(species)"},
+	} {
+		restyResp, err = resty.R().
+			SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+			SetQueryParams(params).
+			Get(h.RestURL("/dinosaurs/aggregate"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restyResp.StatusCode()).To(Equal(http.StatusBadRequest), "%v", params)
+	}
+}
+
+func TestDinosaurExport(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	ctx := h.NewAuthenticatedContext(h.NewRandAccount())
+	jwtToken := ctx.Value(openapi.ContextAccessToken)
+
+	dinos, err := h.Factories.NewDinosaurList("Bronto", 4)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(dinosaurs.Service(&h.Env().Services).Delete(context.Background(), dinos[0].ID)).To(BeNil())
+
+	// the exports aren't paged, and leave the deleted dinosaurs out as the lists do
+	restyResp, err := resty.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+		SetHeader("Accept", "application/x-ndjson").
+		SetQueryParams(map[string]string{"search": "species like 'Bronto%'", "orderBy": "species desc", "size": "1"}).
+		Get(h.RestURL("/dinosaurs"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(restyResp.StatusCode()).To(Equal(http.StatusOK))
+	Expect(restyResp.Header().Get("Content-Type")).To(Equal("application/x-ndjson"))
+
+	lines := strings.Split(strings.TrimSuffix(restyResp.String(), "\n"), "\n")
+	Expect(lines).To(HaveLen(3))
+	for i, species := range []string{"Bronto_4", "Bronto_3", "Bronto_2"} {
+		var dino openapi.Dinosaur
+		Expect(json.Unmarshal([]byte(lines[i]), &dino)).To(Succeed())
+		Expect(dino.Species).To(Equal(species))
+		Expect(*dino.Kind).To(Equal("Dinosaur"))
+	}
+
+	restyResp, err = resty.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+		SetHeader("Accept", "text/csv").
+		SetQueryParams(map[string]string{"fields": "species", "orderBy": "species asc"}).
+		Get(h.RestURL("/dinosaurs"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(restyResp.StatusCode()).To(Equal(http.StatusOK))
+
+	records, err := csv.NewReader(strings.NewReader(restyResp.String())).ReadAll()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(records).To(HaveLen(4))
+	Expect(records[0]).To(Equal([]string{"species", "id"}))
+	for i, dino := range dinos[1:] {
+		Expect(records[i+1]).To(Equal([]string{dino.Species, dino.ID}))
+	}
+
+	restyResp, err = resty.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+		SetHeader("Accept", "text/csv").
+		SetQueryParam("search", "garbage").
+		Get(h.RestURL("/dinosaurs"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(restyResp.StatusCode()).To(Equal(http.StatusBadRequest))
+}
+
+func TestDinosaurTextSearch(t *testing.T) {
+	h, client := test.RegisterIntegration(t)
+
+	account := h.NewRandAccount()
+	ctx := h.NewAuthenticatedContext(account)
+
+	for _, species := range []string{"Tyrannosaurus Rex", "Rex the Rex", "Stegosaurus"} {
+		_, err := h.Factories.NewDinosaur(species)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	// the most relevant dinosaurs come first
+	list, _, err := client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).Q("rex").Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	Expect(list.Items).To(HaveLen(2))
+	Expect(list.Items[0].Species).To(Equal("Rex the Rex"))
+	Expect(list.Items[1].Species).To(Equal("Tyrannosaurus Rex"))
+
+	list, _, err = client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).Q("rex -tyrannosaurus or stegosaurus").OrderBy("species asc").Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	Expect(list.Items).To(HaveLen(2))
+	Expect(list.Items[0].Species).To(Equal("Rex the Rex"))
+	Expect(list.Items[1].Species).To(Equal("Stegosaurus"))
+
+	// the text searches combine with the other filters
+	list, _, err = client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).Q("rex").Search("species like 'Tyrannosaurus%'").Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	Expect(list.Items).To(HaveLen(1))
+	Expect(list.Total).To(Equal(int32(1)))
+}
+
+func TestDinosaurSearchSchema(t *testing.T) {
+	h, client := test.RegisterIntegration(t)
+
+	account := h.NewRandAccount()
+	ctx := h.NewAuthenticatedContext(account)
+
+	_, err := h.Factories.NewDinosaurList("Bronto", 2)
+	Expect(err).NotTo(HaveOccurred())
+
+	list, _, err := client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).Search("created_at > '2000-01-01' and species like 'Bronto%'").OrderBy("updated_at desc").Execute()
+	Expect(err).NotTo(HaveOccurred(), "Error getting dinosaur list: %v", err)
+	Expect(list.Items).To(HaveLen(2))
+
+	// the internal columns and the values of the wrong type are rejected before reaching the database
+	for _, search := range []string{"org_id = 'abc'", "species > 3", "created_at < 'yesterday'"} {
+		_, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).Search(search).Execute()
+		Expect(err).To(HaveOccurred(), search)
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest), search)
+	}
+	_, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).OrderBy("org_id").Execute()
+	Expect(err).To(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+}
+
+func TestDinosaurInclude(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	account := h.NewRandAccount()
+	ctx := h.NewAuthenticatedContext(account)
+	jwtToken := ctx.Value(openapi.ContextAccessToken)
+
+	dinosaur, err := h.Factories.NewDinosaur("Bronto")
+	Expect(err).NotTo(HaveOccurred())
+
+	// dinosaurs have no relations, the includes of the lists and the gets are rejected
+	for _, path := range []string{"/dinosaurs?include=nest", fmt.Sprintf("/dinosaurs/%s?include=nest", dinosaur.ID)} {
+		restyResp, err := resty.R().
+			SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+			Get(h.RestURL(path))
+		Expect(err).NotTo(HaveOccurred(), path)
+		Expect(restyResp.StatusCode()).To(Equal(http.StatusBadRequest), path)
+		Expect(string(restyResp.Body())).To(ContainSubstring("Dinosaur has no relations to include"), path)
+	}
+}
@@ -0,0 +1,80 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/db"
+	"github.com/openshift-online/rh-trex/pkg/services"
+	"github.com/openshift-online/rh-trex/plugins/generic"
+	"github.com/openshift-online/rh-trex/test"
+)
+
+// Egg is a kind of this test only, its properties are stored in a JSONB column as the generated json fields are
+type Egg struct {
+	api.Meta
+	Properties api.Properties `gorm:"type:jsonb;not null;default:'{}'"`
+}
+
+func TestPropertiesSearch(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	g2 := h.Env().Database.SessionFactory.New(context.Background())
+	Expect(g2.AutoMigrate(&Egg{})).To(Succeed())
+	defer func() {
+		Expect(g2.Migrator().DropTable(&Egg{})).To(Succeed())
+	}()
+
+	// registered as the plugin of a kind with a properties:json field registers it
+	services.SearchSchemas["Egg"] = &db.SearchSchema{Table: "eggs", Fields: map[string]db.SearchField{db.PropertiesColumn: {Type: db.SearchProperties}}}
+	defer delete(services.SearchSchemas, "Egg")
+
+	large := &Egg{Meta: api.Meta{ID: api.NewID()}, Properties: api.Properties{
+		"color":   "speckled",
+		"size":    map[string]interface{}{"length": 30, "unit": "cm"},
+		"hatched": true,
+	}}
+	small := &Egg{Meta: api.Meta{ID: api.NewID()}, Properties: api.Properties{
+		"color":   "white",
+		"size":    map[string]interface{}{"length": 8, "unit": "cm"},
+		"hatched": false,
+		"fossil":  "amber",
+	}}
+	plain := &Egg{Meta: api.Meta{ID: api.NewID()}}
+	Expect(g2.Create([]*Egg{large, small, plain}).Error).NotTo(HaveOccurred())
+
+	genericService := generic.Service(&h.Env().Services)
+	ctx := context.Background()
+
+	search := func(search string) []string {
+		var eggs []Egg
+		listArgs := &services.ListArguments{Page: 1, Size: 10, Search: search}
+		_, svcErr := genericService.List(ctx, "username", listArgs, &eggs)
+		Expect(svcErr).To(BeNil(), "search %q", search)
+		var ids []string
+		for _, egg := range eggs {
+			ids = append(ids, egg.ID)
+		}
+		return ids
+	}
+
+	// the properties are compared as text, numbers, booleans and JSON by the right hand side
+	Expect(search("properties.color = 'speckled'")).To(Equal([]string{large.ID}))
+	Expect(search("properties.size.length > 10")).To(Equal([]string{large.ID}))
+	Expect(search("properties.size.length < 40 and properties.size.unit = 'cm'")).To(ConsistOf(large.ID, small.ID))
+	Expect(search("properties.hatched = 'false'")).To(Equal([]string{small.ID}))
+	Expect(search("properties.fossil is not null")).To(Equal([]string{small.ID}))
+	Expect(search("properties.color in ('white', 'brown')")).To(Equal([]string{small.ID}))
+
+	// a property of another type never fails the cast, it doesn't match
+	Expect(search("properties.color > 3")).To(BeEmpty())
+
+	// the other fields are not part of the schema
+	var eggs []Egg
+	listArgs := &services.ListArguments{Page: 1, Size: 10, Search: "color = 'white'"}
+	_, svcErr := genericService.List(ctx, "username", listArgs, &eggs)
+	Expect(svcErr).NotTo(BeNil())
+}
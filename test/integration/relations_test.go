@@ -0,0 +1,94 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/auth"
+	"github.com/openshift-online/rh-trex/pkg/db"
+	"github.com/openshift-online/rh-trex/pkg/services"
+	"github.com/openshift-online/rh-trex/plugins/generic"
+	"github.com/openshift-online/rh-trex/test"
+)
+
+// FossilSite and Fossil are kinds of this test only, a fossil refers to its site as the generated ref fields do
+type FossilSite struct {
+	api.Meta
+	Location string
+}
+
+type Fossil struct {
+	api.Meta
+	Age          int
+	FossilSiteID string
+	FossilSite   *FossilSite `gorm:"foreignKey:FossilSiteID"`
+}
+
+func TestRelations(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	g2 := h.Env().Database.SessionFactory.New(context.Background())
+	Expect(g2.AutoMigrate(&FossilSite{}, &Fossil{})).To(Succeed())
+	defer func() {
+		Expect(g2.Migrator().DropTable(&Fossil{}, &FossilSite{})).To(Succeed())
+	}()
+
+	// registered as the plugin of a kind with a fossil_site ref registers them
+	services.SearchSchemas["FossilSite"] = &db.SearchSchema{Table: "fossil_sites", Fields: map[string]db.SearchField{"location": {Type: db.SearchString}}}
+	services.SearchSchemas["Fossil"] = &db.SearchSchema{Table: "fossils", Fields: map[string]db.SearchField{"age": {Type: db.SearchNumber}}}
+	services.Relations["Fossil"] = map[string]services.Relation{"fossil_site": {Association: "FossilSite", Kind: "FossilSite"}}
+	defer func() {
+		delete(services.SearchSchemas, "FossilSite")
+		delete(services.SearchSchemas, "Fossil")
+		delete(services.Relations, "Fossil")
+	}()
+
+	badlands := &FossilSite{Meta: api.Meta{ID: api.NewID()}, Location: "badlands"}
+	quarry := &FossilSite{Meta: api.Meta{ID: api.NewID()}, Location: "quarry"}
+	Expect(g2.Create([]*FossilSite{badlands, quarry}).Error).NotTo(HaveOccurred())
+	femur := &Fossil{Meta: api.Meta{ID: api.NewID()}, Age: 66, FossilSiteID: badlands.ID}
+	skull := &Fossil{Meta: api.Meta{ID: api.NewID()}, Age: 150, FossilSiteID: quarry.ID}
+	Expect(g2.Create([]*Fossil{femur, skull}).Error).NotTo(HaveOccurred())
+
+	genericService := generic.Service(&h.Env().Services)
+	ctx := context.Background()
+
+	// the searches use the fields of the related kind by the name of the relation, and the lists include it
+	var fossils []Fossil
+	listArgs := &services.ListArguments{Page: 1, Size: 10, Search: "fossil_site.location = 'badlands' and age > 10", Preloads: []string{"fossil_site"}}
+	_, svcErr := genericService.List(ctx, "username", listArgs, &fossils)
+	Expect(svcErr).To(BeNil())
+	Expect(fossils).To(HaveLen(1))
+	Expect(fossils[0].ID).To(Equal(femur.ID))
+	Expect(fossils[0].FossilSite).NotTo(BeNil())
+	Expect(fossils[0].FossilSite.Location).To(Equal("badlands"))
+
+	// the fields of the related kind are checked by its search schema
+	listArgs = &services.ListArguments{Page: 1, Size: 10, Search: "fossil_site.depth = 3"}
+	_, svcErr = genericService.List(ctx, "username", listArgs, &fossils)
+	Expect(svcErr).NotTo(BeNil())
+	Expect(svcErr.Reason).To(Equal("depth is not a valid field name, the fields are location"))
+
+	// the searches only match the related records of the caller's organization
+	tenantCtx := auth.SetTenantContext(context.Background(), h.NewID())
+	tooth := &Fossil{Meta: api.Meta{ID: api.NewID()}, Age: 70, FossilSiteID: badlands.ID}
+	Expect(h.Env().Database.SessionFactory.New(tenantCtx).Create(tooth).Error).NotTo(HaveOccurred())
+	listArgs = &services.ListArguments{Page: 1, Size: 10, Search: "fossil_site.location = 'badlands'"}
+	_, svcErr = genericService.List(tenantCtx, "username", listArgs, &fossils)
+	Expect(svcErr).To(BeNil())
+	Expect(fossils).To(BeEmpty())
+	_, svcErr = genericService.List(ctx, "username", listArgs, &fossils)
+	Expect(svcErr).To(BeNil())
+	Expect(fossils).To(HaveLen(2))
+
+	// the gets include it as well
+	fossil := &Fossil{}
+	Expect(g2.Take(fossil, "id = ?", skull.ID).Error).NotTo(HaveOccurred())
+	Expect(fossil.FossilSite).To(BeNil())
+	Expect(genericService.Include(ctx, []string{"fossil_site"}, fossil)).To(BeNil())
+	Expect(fossil.FossilSite).NotTo(BeNil())
+	Expect(fossil.FossilSite.ID).To(Equal(quarry.ID))
+}
@@ -0,0 +1,111 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"gopkg.in/resty.v1"
+
+	"github.com/openshift-online/rh-trex/pkg/api/openapi"
+	"github.com/openshift-online/rh-trex/pkg/api/presenters"
+	"github.com/openshift-online/rh-trex/test"
+
+	_ "github.com/openshift-online/rh-trex/plugins/savedsearches"
+)
+
+func TestSavedSearchViews(t *testing.T) {
+	h, client := test.RegisterIntegration(t)
+
+	account := h.NewRandAccount()
+	ctx := h.NewAuthenticatedContext(account)
+	jwtToken := ctx.Value(openapi.ContextAccessToken)
+
+	_, err := h.Factories.NewDinosaurList("Stego", 3)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = h.Factories.NewDinosaurList("Bronto", 2)
+	Expect(err).NotTo(HaveOccurred())
+
+	saveSearch := func(token interface{}, body string) *resty.Response {
+		restyResp, err := resty.R().
+			SetHeader("Content-Type", "application/json").
+			SetHeader("Authorization", fmt.Sprintf("Bearer %s", token)).
+			SetBody(body).
+			Post(h.RestURL("/saved_searches"))
+		Expect(err).NotTo(HaveOccurred())
+		return restyResp
+	}
+
+	restyResp := saveSearch(jwtToken, `{"name": "stegos", "resource_kind": "Dinosaur", "search": "species like 'Stego%'", "order_by": "species desc", "fields": "species"}`)
+	Expect(restyResp.StatusCode()).To(Equal(http.StatusCreated))
+	var savedSearch presenters.SavedSearch
+	Expect(json.Unmarshal(restyResp.Body(), &savedSearch)).To(Succeed())
+	Expect(savedSearch.Owner).To(Equal(account.Username()))
+	Expect(*savedSearch.Kind).To(Equal("SavedSearch"))
+
+	// the names are unique among the saved searches of the owner for the kind, and the queries must parse
+	Expect(saveSearch(jwtToken, `{"name": "stegos", "resource_kind": "Dinosaur"}`).StatusCode()).To(Equal(http.StatusConflict))
+	Expect(saveSearch(jwtToken, `{"name": "broken", "resource_kind": "Dinosaur", "search": "species = "}`).StatusCode()).To(Equal(http.StatusBadRequest))
+	Expect(saveSearch(jwtToken, `{"name": "eggs", "resource_kind": "Egg"}`).StatusCode()).To(Equal(http.StatusBadRequest))
+
+	// the view is expanded into the search, the ordering and the fields of the list
+	listView := func(params map[string]string) presenters.ProjectionList {
+		var list presenters.ProjectionList
+		restyResp, err := resty.R().
+			SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+			SetQueryParams(params).
+			SetResult(&list).
+			Get(h.RestURL("/dinosaurs"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restyResp.StatusCode()).To(Equal(http.StatusOK))
+		return list
+	}
+	list := listView(map[string]string{"view": "stegos"})
+	Expect(list.Items).To(HaveLen(3))
+	Expect(list.Items[0]).To(HaveKeyWithValue("species", "Stego_3"))
+	Expect(list.Items[0]).NotTo(HaveKey("created_at"))
+
+	// the search of the list narrows the one of the view, its ordering replaces the one of the view
+	list = listView(map[string]string{"view": "stegos", "search": "species != 'Stego_3'", "orderBy": "species asc"})
+	Expect(list.Items).To(HaveLen(2))
+	Expect(list.Items[0]).To(HaveKeyWithValue("species", "Stego_1"))
+
+	// saved searches which no longer apply to the kind are rejected when used
+	restyResp, err = resty.R().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+		SetBody(`{"search": "color = 'green'"}`).
+		Patch(h.RestURL(fmt.Sprintf("/saved_searches/%s", *savedSearch.Id)))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(restyResp.StatusCode()).To(Equal(http.StatusOK))
+	_, resp, err := client.DefaultAPI.ApiRhTrexV1DinosaursGet(ctx).View("stegos").Execute()
+	Expect(err).To(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+	// the saved searches are their owner's own
+	otherCtx := h.NewAuthenticatedContext(h.NewRandAccount())
+	otherToken := otherCtx.Value(openapi.ContextAccessToken)
+	_, resp, err = client.DefaultAPI.ApiRhTrexV1DinosaursGet(otherCtx).View("stegos").Execute()
+	Expect(err).To(HaveOccurred())
+	Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	restyResp, err = resty.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", otherToken)).
+		Get(h.RestURL(fmt.Sprintf("/saved_searches/%s", *savedSearch.Id)))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(restyResp.StatusCode()).To(Equal(http.StatusNotFound))
+
+	restyResp, err = resty.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+		Delete(h.RestURL(fmt.Sprintf("/saved_searches/%s", *savedSearch.Id)))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(restyResp.StatusCode()).To(Equal(http.StatusNoContent))
+	restyResp, err = resty.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", jwtToken)).
+		Get(h.RestURL("/saved_searches"))
+	Expect(err).NotTo(HaveOccurred())
+	var savedSearches presenters.SavedSearchList
+	Expect(json.Unmarshal(restyResp.Body(), &savedSearches)).To(Succeed())
+	Expect(savedSearches.Items).To(BeEmpty())
+}
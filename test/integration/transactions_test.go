@@ -0,0 +1,134 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/openshift-online/rh-trex/pkg/api"
+	"github.com/openshift-online/rh-trex/pkg/dao"
+	"github.com/openshift-online/rh-trex/pkg/db"
+	"github.com/openshift-online/rh-trex/pkg/db/db_context"
+	"github.com/openshift-online/rh-trex/test"
+)
+
+func TestWithSavepoint(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	sessionFactory := h.Env().Database.SessionFactory
+	ctx, err := db.NewContext(context.Background(), sessionFactory)
+	Expect(err).NotTo(HaveOccurred())
+	tx, _ := db_context.Transaction(ctx)
+
+	insert := func(ctx context.Context, id string) error {
+		_, err := tx.Tx().Exec("insert into dinosaurs (id, created_at, updated_at, species) values ($1, now(), now(), 'savepoint')", id)
+		return err
+	}
+
+	kept, nested, failed := h.NewID(), h.NewID(), h.NewID()
+	Expect(insert(ctx, kept)).To(Succeed())
+
+	// a successful sub-operation keeps its changes
+	err = db.WithSavepoint(ctx, func(ctx context.Context) error {
+		return insert(ctx, nested)
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	// a failed sub-operation is rolled back along with its MarkForRollback, the transaction goes on
+	boom := errors.New("boom")
+	err = db.WithSavepoint(ctx, func(ctx context.Context) error {
+		Expect(insert(ctx, failed)).To(Succeed())
+		db.MarkForRollback(ctx, boom)
+		return boom
+	})
+	Expect(err).To(MatchError(boom))
+	Expect(tx.MarkedForRollback()).To(BeFalse())
+
+	// a failed statement aborts the postgres transaction, the savepoint recovers it
+	err = db.WithSavepoint(ctx, func(ctx context.Context) error {
+		return insert(ctx, kept)
+	})
+	Expect(err).To(HaveOccurred())
+
+	db.Resolve(ctx)
+
+	var dinosaurs []api.Dinosaur
+	g2 := sessionFactory.New(context.Background())
+	Expect(g2.Where("id in ?", []string{kept, nested, failed}).Find(&dinosaurs).Error).NotTo(HaveOccurred())
+	ids := []string{}
+	for _, d := range dinosaurs {
+		ids = append(ids, d.ID)
+	}
+	Expect(ids).To(ConsistOf(kept, nested))
+}
+
+func TestRequestTransactionRollsBackDaoWrites(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	sessionFactory := h.Env().Database.SessionFactory
+	dinoDao := dao.NewDinosaurDao(&sessionFactory)
+	eventDao := dao.NewEventDao(&sessionFactory)
+
+	// reject the events of this test only, from outside the request transaction
+	g2 := sessionFactory.New(context.Background())
+	Expect(g2.Exec("alter table events add constraint test_failing_events check (source <> 'FailingDinosaurs') not valid").Error).NotTo(HaveOccurred())
+	defer g2.Exec("alter table events drop constraint test_failing_events")
+
+	// the dinosaur and its event commit together
+	ctx, err := db.NewContext(context.Background(), sessionFactory)
+	Expect(err).NotTo(HaveOccurred())
+	committed, err := dinoDao.Create(ctx, &api.Dinosaur{Species: "committed"})
+	Expect(err).NotTo(HaveOccurred())
+	_, err = eventDao.Create(ctx, &api.Event{Source: "Dinosaurs", SourceID: committed.ID, EventType: api.CreateEventType})
+	Expect(err).NotTo(HaveOccurred())
+
+	// uncommitted writes are not visible outside of the transaction
+	_, err = dinoDao.Get(context.Background(), committed.ID)
+	Expect(err).To(HaveOccurred())
+
+	db.Resolve(ctx)
+	_, err = dinoDao.Get(context.Background(), committed.ID)
+	Expect(err).NotTo(HaveOccurred())
+
+	// a failed event insert marks the request transaction for rollback, which undoes the dinosaur
+	ctx, err = db.NewContext(context.Background(), sessionFactory)
+	Expect(err).NotTo(HaveOccurred())
+	rolledBack, err := dinoDao.Create(ctx, &api.Dinosaur{Species: "rolled back"})
+	Expect(err).NotTo(HaveOccurred())
+	_, err = eventDao.Create(ctx, &api.Event{Source: "FailingDinosaurs", SourceID: rolledBack.ID, EventType: api.CreateEventType})
+	Expect(err).To(HaveOccurred())
+
+	db.Resolve(ctx)
+	_, err = dinoDao.Get(context.Background(), rolledBack.ID)
+	Expect(err).To(MatchError(gorm.ErrRecordNotFound))
+}
+
+func TestWithTransaction(t *testing.T) {
+	h, _ := test.RegisterIntegration(t)
+
+	sessionFactory := h.Env().Database.SessionFactory
+	dinoDao := dao.NewDinosaurDao(&sessionFactory)
+
+	var committed, rolledBack *api.Dinosaur
+	err := db.WithTransaction(context.Background(), sessionFactory, func(ctx context.Context) (err error) {
+		committed, err = dinoDao.Create(ctx, &api.Dinosaur{Species: "committed"})
+		return err
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	boom := errors.New("boom")
+	err = db.WithTransaction(context.Background(), sessionFactory, func(ctx context.Context) (err error) {
+		rolledBack, err = dinoDao.Create(ctx, &api.Dinosaur{Species: "rolled back"})
+		Expect(err).NotTo(HaveOccurred())
+		return boom
+	})
+	Expect(err).To(MatchError(boom))
+
+	_, err = dinoDao.Get(context.Background(), committed.ID)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = dinoDao.Get(context.Background(), rolledBack.ID)
+	Expect(err).To(MatchError(gorm.ErrRecordNotFound))
+}